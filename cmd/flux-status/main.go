@@ -2,9 +2,12 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"os/signal"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -13,10 +16,21 @@ import (
 	"github.com/go-logr/zapr"
 	flag "github.com/spf13/pflag"
 	"go.uber.org/zap"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 
 	"github.com/xenitab/flux-status/pkg/api"
+	"github.com/xenitab/flux-status/pkg/flux"
+	"github.com/xenitab/flux-status/pkg/history"
+	"github.com/xenitab/flux-status/pkg/informer"
+	"github.com/xenitab/flux-status/pkg/leader"
+	"github.com/xenitab/flux-status/pkg/metrics"
 	"github.com/xenitab/flux-status/pkg/notifier"
 	"github.com/xenitab/flux-status/pkg/poller"
+	"github.com/xenitab/flux-status/pkg/reconciler"
+	"github.com/xenitab/flux-status/pkg/state"
+	"github.com/xenitab/flux-status/pkg/stream"
+	"github.com/xenitab/flux-status/pkg/tracing"
 )
 
 func getLogger(debug bool) (logr.Logger, error) {
@@ -35,15 +49,75 @@ func getLogger(debug bool) (logr.Logger, error) {
 	return zapr.NewLogger(zapLog), nil
 }
 
+// getKubernetesClient returns a client using the in cluster Kubernetes configuration.
+func getKubernetesClient() (kubernetes.Interface, error) {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return kubernetes.NewForConfig(config)
+}
+
+// getElector returns an Elector using the in cluster Kubernetes configuration.
+func getElector(log logr.Logger, namespace string, name string, identity string, lead func(context.Context)) (*leader.Elector, error) {
+	if identity == "" {
+		return nil, errors.New("Advertise address can't be empty with leader election")
+	}
+
+	client, err := getKubernetesClient()
+	if err != nil {
+		return nil, err
+	}
+
+	if namespace == "" {
+		b, err := ioutil.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/namespace")
+		if err != nil {
+			return nil, fmt.Errorf("Could not get namespace of pod: %w", err)
+		}
+		namespace = strings.TrimSpace(string(b))
+	}
+
+	return leader.NewElector(log, client, namespace, name, identity, lead)
+}
+
 func main() {
 	// Flags
 	debug := flag.Bool("debug", false, "Enables debug mode.")
 	listenAddr := flag.String("listen", ":3000", "Address to serve events API on.")
-	fluxAddr := flag.String("flux", "localhost:3030", "Address to communicate with the Flux API through.")
+	token := flag.String("token", "", "Token Flux authenticates with, should be same as the flux --token flag.")
+	signatureKey := flag.String("signature-key", "", "Key to verify the HMAC-SHA256 signature of event request bodies with.")
+	fluxAddr := flag.String("flux", "localhost:3030", "Address to communicate with the Flux API through, uses the upstream connection from Flux if empty.")
 	instance := flag.String("instance", "default", "Id to differentiate between multiple flux-status updating the same repository.")
+	reportIncluded := flag.Bool("report-included-commits", true, "Enables sync statuses for all commits included in a sync, not only the head commit.")
 	enablePoller := flag.Bool("poll-workloads", true, "Enables polling of workloads after sync.")
 	pollInterval := flag.Int("poll-intervall", 5, "Duration in seconds between each service poll.")
 	pollTimeout := flag.Int("poll-timeout", 360, "Duration in seconds before stopping poll.")
+	pollNamespaces := flag.StringSlice("poll-namespaces", []string{}, "Namespaces to poll workloads in, all namespaces are polled if empty.")
+	pollExcludeNamespaces := flag.StringSlice("poll-exclude-namespaces", []string{}, "Namespaces to never poll workloads in.")
+	pollInclude := flag.StringSlice("poll-include", []string{}, "Patterns of workload ids or kinds to evaluate when polling, all workloads are evaluated if empty.")
+	pollExclude := flag.StringSlice("poll-exclude", []string{}, "Patterns of workload ids or kinds to never evaluate when polling.")
+	pollChangedOnly := flag.Bool("poll-changed-only", false, "Only poll the workloads changed by a sync, and the workloads in the namespace of a changed ConfigMap or Secret.")
+	healthRulesPath := flag.String("health-rules", "", "Path of a YAML file with the rules deciding the health of each workload kind, the default rules are used if empty.")
+	healthChecker := flag.String("health-checker", "flux", "Backend to evaluate workload health with, either flux to poll the Flux API or kubernetes to watch the workloads.")
+	pollProgressInterval := flag.Int("poll-progress-interval", poller.DefaultProgressInterval, "Minimum duration in seconds between pending workload statuses sent while polling.")
+	enableReconciler := flag.Bool("reconcile", true, "Enables reconciliation of incomplete statuses on startup.")
+	reconcileInterval := flag.Int("reconcile-interval", 10, "Duration in seconds between each reconcile attempt.")
+	reconcileTimeout := flag.Int("reconcile-timeout", 300, "Duration in seconds before giving up reconciliation.")
+	enableComments := flag.Bool("comment-failures", true, "Enables comments on commits and pull requests when an event fails.")
+	stateLimit := flag.Int("state-limit", 100, "Number of commits to keep the latest known state of in memory.")
+	streamBuffer := flag.Int("stream-buffer", 1000, "Number of status stream messages to keep for replay.")
+	historyPath := flag.String("history-path", "", "Path of the file to persist the deployment history in, history is disabled if empty.")
+	historyRetention := flag.Int("history-retention", 30, "Duration in days to keep the deployment history.")
+	historyLimit := flag.Int("history-limit", 10000, "Max number of entries to keep in the deployment history.")
+	leaderElect := flag.Bool("leader-elect", false, "Enables leader election when running multiple replicas, only the leader sends statuses.")
+	leaderElectNamespace := flag.String("leader-elect-namespace", "", "Namespace of the leader election Lease, defaults to the namespace of the pod.")
+	leaderElectName := flag.String("leader-elect-name", "flux-status", "Name of the leader election Lease.")
+	advertiseAddr := flag.String("advertise-address", "", "Address other replicas forward events to when this replica is the leader, required with leader election.")
+	shutdownTimeout := flag.Int("shutdown-timeout", 25, "Duration in seconds to wait for event handlers and final poll statuses when shutting down.")
+	tracingExporter := flag.String("tracing-exporter", "none", "Exporter to send traces with, one of none, otlp or stdout.")
+	tracingEndpoint := flag.String("tracing-endpoint", "", "Address of the OTLP collector, defaults to localhost:55680.")
+	tracingRatio := flag.Float64("tracing-sample-ratio", 1, "Ratio of traces to sample between 0 and 1.")
 	gitURL := flag.String("git-url", "", "URL for git repository, should be same as flux.")
 	azdoPat := flag.String("azdo-pat", "", "Tokent to authenticate with Azure DevOps.")
 	glToken := flag.String("gitlab-token", "", "Token to authenticate with Gitlab.")
@@ -58,17 +132,98 @@ func main() {
 	setupLog := log.WithName("setup")
 	setupLog.Info("Staring flux-status")
 
+	// Tracing
+	shutdownTracing, err := tracing.Setup(*tracingExporter, *tracingEndpoint, *tracingRatio)
+	if err != nil {
+		setupLog.Error(err, "Error setting up tracing", "exporter", tracingExporter)
+		os.Exit(1)
+	}
+
 	// Get Notifier
-	notifier, err := notifier.GetNotifier(*instance, *gitURL, *azdoPat, *glToken, *ghToken)
+	var noti notifier.Notifier
+	noti, err = notifier.GetNotifier(*instance, *gitURL, *azdoPat, *glToken, *ghToken)
 	if err != nil {
 		setupLog.Error(err, "Error getting Notifier", "url", gitURL)
 		os.Exit(1)
 	}
-	setupLog.Info("Using notifier", "name", notifier.String())
+	setupLog.Info("Using notifier", "name", noti.String())
+	noti = metrics.NewNotifier(noti)
+	noti = tracing.NewNotifier(noti)
+	if *enableComments {
+		noti = notifier.NewCommentNotifier(log.WithName("comment"), noti)
+	}
+	var historyStore *history.Store
+	if *historyPath != "" {
+		historyStore, err = history.Open(*historyPath, *instance, time.Duration(*historyRetention)*24*time.Hour, *historyLimit)
+		if err != nil {
+			setupLog.Error(err, "Error opening history", "path", historyPath)
+			os.Exit(1)
+		}
+		defer historyStore.Close()
+		noti = history.NewNotifier(log.WithName("history"), noti, historyStore)
+	}
+	statusState := state.NewState(*instance, *stateLimit)
+	noti = state.NewRecorder(noti, statusState)
+	broker := stream.NewBroker(*streamBuffer)
+	noti = stream.NewPublisher(noti, broker)
+
+	pollFilter, err := poller.NewFilter(*pollInclude, *pollExclude)
+	if err != nil {
+		setupLog.Error(err, "Error parsing workload patterns")
+		os.Exit(1)
+	}
+
+	healthRules := poller.DefaultHealthRules()
+	if *healthRulesPath != "" {
+		healthRules, err = poller.LoadHealthRules(*healthRulesPath)
+		if err != nil {
+			setupLog.Error(err, "Error loading health rules", "path", *healthRulesPath)
+			os.Exit(1)
+		}
+	}
+
+	// Get Flux client
+	upstream := flux.NewUpstream()
+	var fluxClient flux.Client = upstream
+	if *fluxAddr != "" {
+		fluxClient, err = flux.NewClient(*fluxAddr)
+		if err != nil {
+			setupLog.Error(err, "Error creating Flux client", "address", fluxAddr)
+			os.Exit(1)
+		}
+	}
+
+	// Get health checker, the poller uses the Flux client if nil
+	var checker poller.HealthChecker
+	checkerStop := make(chan struct{})
+	defer close(checkerStop)
+	switch *healthChecker {
+	case "flux":
+	case "kubernetes":
+		client, err := getKubernetesClient()
+		if err != nil {
+			setupLog.Error(err, "Error creating Kubernetes client")
+			os.Exit(1)
+		}
+		h := informer.NewHealthChecker(log.WithName("health-checker"), client, 10*time.Minute)
+		if err := h.Start(checkerStop); err != nil {
+			setupLog.Error(err, "Error starting health checker")
+			os.Exit(1)
+		}
+		checker = h
+		if *healthRulesPath == "" {
+			healthRules = informer.DefaultHealthRules()
+		}
+	default:
+		setupLog.Error(errors.New("Unknown health checker"), "Error getting health checker", "name", *healthChecker)
+		os.Exit(1)
+	}
 
 	// Setup
 	shutdownWg := &sync.WaitGroup{}
 	shutdown := make(chan struct{})
+	// shutdownCtx is set before shutdown is closed and limits the whole shutdown
+	var shutdownCtx context.Context
 	errc := make(chan error)
 	go func() {
 		c := make(chan os.Signal, 1)
@@ -77,49 +232,133 @@ func main() {
 	}()
 
 	// Channel is nil if poller is not enabled
-	var events chan string = nil
-
-	// Start Poller
+	var events chan notifier.Event = nil
 	if *enablePoller {
-		events = make(chan string, 1)
-		shutdownWg.Add(1)
-		p, err := poller.NewPoller(log.WithName("poller"), notifier, events, *fluxAddr, *pollInterval, *pollTimeout)
-		if err != nil {
-			errc <- err
+		events = make(chan notifier.Event, 1)
+	}
+
+	// lead runs the components sending statuses until the context is canceled or shutdown
+	leadMu := &sync.Mutex{}
+	leadWg := &sync.WaitGroup{}
+	shuttingDown := false
+	lead := func(ctx context.Context) {
+		leadMu.Lock()
+		if shuttingDown {
+			leadMu.Unlock()
+			return
 		}
-		go p.Start()
-		go func() {
-			defer shutdownWg.Done()
-			<-shutdown
-			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-			defer cancel()
-			if err := p.Stop(ctx); err != nil {
+		leadWg.Add(1)
+		leadMu.Unlock()
+		defer leadWg.Done()
+
+		// Start Poller
+		var p *poller.Poller
+		if *enablePoller {
+			p = poller.NewPoller(log.WithName("poller"), noti, events, fluxClient, *pollInterval, *pollTimeout)
+			p.Broker = broker
+			p.History = historyStore
+			p.ProgressInterval = *pollProgressInterval
+			p.Namespaces = *pollNamespaces
+			p.ExcludeNamespaces = *pollExcludeNamespaces
+			p.Filter = pollFilter
+			p.ChangedOnly = *pollChangedOnly
+			p.Rules = healthRules
+			p.Checker = checker
+			go p.Start()
+		}
+
+		// Start Reconciler
+		if *enableReconciler {
+			r := reconciler.NewReconciler(log.WithName("reconciler"), noti, fluxClient, events, *reconcileInterval)
+			go func() {
+				ctx, cancel := context.WithTimeout(ctx, time.Duration(*reconcileTimeout)*time.Second)
+				defer cancel()
+				if err := r.Reconcile(ctx); err != nil {
+					setupLog.Error(err, "Error occured when reconciling statuses")
+				}
+			}()
+		}
+
+		var stopCtx context.Context
+		var stopCancel context.CancelFunc
+		handover := false
+		select {
+		case <-ctx.Done():
+			// The leadership was lost, the new leader finishes the statuses
+			stopCtx, stopCancel = context.WithTimeout(context.Background(), 5*time.Second)
+			handover = true
+		case <-shutdown:
+			stopCtx, stopCancel = context.WithCancel(shutdownCtx)
+		}
+		defer stopCancel()
+		if p != nil {
+			stop := p.Stop
+			if handover {
+				stop = p.Handover
+			}
+			if err := stop(stopCtx); err != nil {
 				setupLog.Error(err, "Error occured when stopping poller")
 			}
 			setupLog.Info("Stopped poller")
+		}
+	}
+
+	// Start leading, either directly or when elected
+	var elector *leader.Elector
+	leadCtx, leadCancel := context.WithCancel(context.Background())
+	shutdownWg.Add(2)
+	if *leaderElect {
+		elector, err = getElector(log.WithName("leader"), *leaderElectNamespace, *leaderElectName, *advertiseAddr, lead)
+		if err != nil {
+			setupLog.Error(err, "Error setting up leader election")
+			os.Exit(1)
+		}
+		go func() {
+			defer shutdownWg.Done()
+			elector.Run(leadCtx)
+		}()
+	} else {
+		go func() {
+			defer shutdownWg.Done()
+			lead(leadCtx)
 		}()
 	}
+	go func() {
+		defer shutdownWg.Done()
+		<-shutdown
+		// The lease is released when canceled so the components have to be stopped first
+		leadMu.Lock()
+		shuttingDown = true
+		leadMu.Unlock()
+		leadWg.Wait()
+		leadCancel()
+	}()
 
 	// Start Server
-	shutdownWg.Add(1)
-	apiServer := api.NewServer(notifier, events, log.WithName("api-server"))
+	apiServer := api.NewServer(noti, events, log.WithName("api-server"), *reportIncluded, upstream, *token, *signatureKey)
+	apiServer.Flux = fluxClient
+	apiServer.State = statusState
+	apiServer.Broker = broker
+	apiServer.History = historyStore
+	apiServer.Elector = elector
 	go func() {
 		errc <- apiServer.Start(*listenAddr)
 	}()
-	go func() {
-		defer shutdownWg.Done()
-		<-shutdown
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		if err := apiServer.Stop(ctx); err != nil {
-			setupLog.Error(err, "Error occured when stopping server")
-		}
-		setupLog.Info("Stopped server")
-	}()
 
 	// Wait until stop signal or error
 	setupLog.Error(<-errc, "Stopping flux-status")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(*shutdownTimeout)*time.Second)
+	defer cancel()
+	// The server is stopped first so that event handlers in progress can still hand over to the poller
+	if err := apiServer.Stop(ctx); err != nil {
+		setupLog.Error(err, "Error occured when stopping server")
+	}
+	setupLog.Info("Stopped server")
+	shutdownCtx = ctx
 	close(shutdown)
 	shutdownWg.Wait()
+	if err := shutdownTracing(ctx); err != nil {
+		setupLog.Error(err, "Error occured when flushing traces")
+	}
 	setupLog.Info("Stopped flux-status")
 }
@@ -8,14 +8,24 @@ require (
 	github.com/go-logr/zapr v0.2.0
 	github.com/google/go-github/v32 v32.1.0
 	github.com/gorilla/mux v1.7.4
-	github.com/gorilla/websocket v1.4.2
+	github.com/gorilla/websocket v1.4.2 // indirect
 	github.com/microsoft/azure-devops-go-api/azuredevops v1.0.0-b3
 	github.com/onsi/gomega v1.10.1
+	github.com/prometheus/client_golang v1.2.1
 	github.com/spf13/pflag v1.0.5
 	github.com/xanzy/go-gitlab v0.33.0
+	go.etcd.io/bbolt v1.3.5
+	go.opentelemetry.io/otel v0.13.0
+	go.opentelemetry.io/otel/exporters/otlp v0.13.0
+	go.opentelemetry.io/otel/exporters/stdout v0.13.0
+	go.opentelemetry.io/otel/sdk v0.13.0
 	go.uber.org/goleak v1.1.10
 	go.uber.org/zap v1.15.0
 	golang.org/x/oauth2 v0.0.0-20190604053449-0f29369cfe45
+	k8s.io/api v0.17.4
+	k8s.io/apimachinery v0.17.4
+	k8s.io/client-go v11.0.0+incompatible
+	sigs.k8s.io/yaml v1.1.0
 )
 
 replace (
@@ -23,4 +33,5 @@ replace (
 	github.com/fluxcd/flux/pkg/install => github.com/fluxcd/flux/pkg/install v0.0.0-20200205115544-4fc656b636e3
 	github.com/fluxcd/helm-operator => github.com/fluxcd/helm-operator v1.0.0-rc9
 	github.com/fluxcd/helm-operator/pkg/install => github.com/fluxcd/helm-operator/pkg/install v0.0.0-20200213151218-f7e487142b46
+	k8s.io/client-go => k8s.io/client-go v0.17.4
 )
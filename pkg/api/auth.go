@@ -0,0 +1,82 @@
+package api
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/gorilla/mux"
+)
+
+// SignatureHeader is the header containing the hex encoded HMAC-SHA256 signature of the request body.
+const SignatureHeader = "X-Signature"
+
+// authMiddleware rejects requests that neither carry the token nor a valid signature of the body.
+// Requests are let through if no token or signature key is configured.
+func authMiddleware(log logr.Logger, token string, signatureKey string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if token == "" && signatureKey == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if token != "" && validToken(r, token) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if signatureKey != "" {
+				body, err := ioutil.ReadAll(r.Body)
+				if err != nil {
+					log.Error(err, "Could not read request body")
+					http.Error(w, err.Error(), 400)
+					return
+				}
+				r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+				if validSignature(r.Header.Get(SignatureHeader), body, signatureKey) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			log.Info("Rejected unauthenticated request", "url", r.URL, "remote-addr", r.RemoteAddr)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		})
+	}
+}
+
+// validToken checks the Authorization header for the token, either in the
+// format used by Flux when connecting to its upstream or as a bearer token.
+func validToken(r *http.Request, token string) bool {
+	header := r.Header.Get("Authorization")
+	for _, prefix := range []string{"Scope-Probe token=", "Bearer "} {
+		if !strings.HasPrefix(header, prefix) {
+			continue
+		}
+
+		value := strings.TrimPrefix(header, prefix)
+		return subtle.ConstantTimeCompare([]byte(value), []byte(token)) == 1
+	}
+
+	return false
+}
+
+// validSignature checks that the signature is the HMAC-SHA256 of the body, optionally prefixed with "sha256=".
+func validSignature(signature string, body []byte, key string) bool {
+	expected, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil || len(expected) == 0 {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	logr "github.com/go-logr/logr/testing"
+	"github.com/onsi/gomega"
+)
+
+func authTestHandler(t *testing.T, token string, key string) http.Handler {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = w.Write(body)
+	})
+	return authMiddleware(logr.TestLogger{T: t}, token, key)(next)
+}
+
+func TestAuthDisabled(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	req := httptest.NewRequest("POST", "/v6/events", nil)
+	rr := httptest.NewRecorder()
+	authTestHandler(t, "", "").ServeHTTP(rr, req)
+	g.Expect(rr.Code).Should(gomega.Equal(http.StatusOK))
+}
+
+func TestAuthToken(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	for _, header := range []string{"Scope-Probe token=foobar", "Bearer foobar"} {
+		req := httptest.NewRequest("POST", "/v6/events", nil)
+		req.Header.Set("Authorization", header)
+		rr := httptest.NewRecorder()
+		authTestHandler(t, "foobar", "").ServeHTTP(rr, req)
+		g.Expect(rr.Code).Should(gomega.Equal(http.StatusOK))
+	}
+}
+
+func TestAuthInvalidToken(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	for _, header := range []string{"", "Scope-Probe token=barfoo", "foobar"} {
+		req := httptest.NewRequest("POST", "/v6/events", nil)
+		req.Header.Set("Authorization", header)
+		rr := httptest.NewRecorder()
+		authTestHandler(t, "foobar", "").ServeHTTP(rr, req)
+		g.Expect(rr.Code).Should(gomega.Equal(http.StatusUnauthorized))
+	}
+}
+
+func TestAuthSignature(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	body := []byte(`{"type":"sync"}`)
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(body)
+	signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	req := httptest.NewRequest("POST", "/v6/events", bytes.NewReader(body))
+	req.Header.Set(SignatureHeader, signature)
+	rr := httptest.NewRecorder()
+	authTestHandler(t, "foobar", "secret").ServeHTTP(rr, req)
+	g.Expect(rr.Code).Should(gomega.Equal(http.StatusOK))
+	g.Expect(rr.Body.Bytes()).Should(gomega.Equal(body))
+
+	req = httptest.NewRequest("POST", "/v6/events", bytes.NewReader([]byte(`{"type":"commit"}`)))
+	req.Header.Set(SignatureHeader, signature)
+	rr = httptest.NewRecorder()
+	authTestHandler(t, "foobar", "secret").ServeHTTP(rr, req)
+	g.Expect(rr.Code).Should(gomega.Equal(http.StatusUnauthorized))
+}
@@ -0,0 +1,263 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/go-logr/logr"
+)
+
+// dashboardHandler serves a single page dashboard built on the status query and stream endpoints.
+// All styles and scripts are inlined so that the dashboard works without access to external assets.
+func dashboardHandler(log logr.Logger) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-cache")
+		if _, err := w.Write([]byte(dashboardHTML)); err != nil {
+			log.Error(err, "Could not write dashboard")
+		}
+	})
+}
+
+const dashboardHTML = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Flux Status</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; margin: 0; background: #f6f8fa; color: #24292e; }
+header { background: #24292e; color: #fff; padding: 12px 24px; display: flex; justify-content: space-between; align-items: center; }
+header h1 { font-size: 18px; margin: 0; }
+main { padding: 0 24px 24px; max-width: 1200px; }
+section { background: #fff; border: 1px solid #e1e4e8; border-radius: 6px; margin-top: 24px; padding: 16px; }
+h2 { font-size: 16px; margin: 0 0 12px; }
+table { border-collapse: collapse; width: 100%; font-size: 14px; }
+th, td { text-align: left; padding: 6px 8px; border-bottom: 1px solid #e1e4e8; vertical-align: top; }
+code { font-family: SFMono-Regular, Consolas, Menlo, monospace; font-size: 13px; }
+ul { margin: 4px 0; padding-left: 20px; }
+.muted { color: #6a737d; }
+.state { display: inline-block; border-radius: 12px; padding: 1px 8px; font-size: 12px; color: #fff; background: #6a737d; }
+.state-succeeded { background: #28a745; }
+.state-failed { background: #d73a49; }
+.state-pending { background: #dbab09; }
+.state-canceled { background: #6a737d; }
+.errors { color: #d73a49; }
+</style>
+</head>
+<body>
+<header>
+  <h1>Flux Status <span id="instance" class="muted"></span></h1>
+  <span id="connection" class="muted">Connecting...</span>
+</header>
+<main>
+  <section>
+    <h2>Current commit</h2>
+    <div id="current" class="muted">No sync received yet.</div>
+  </section>
+  <section>
+    <h2>Workload poll</h2>
+    <div id="poll" class="muted">No poll in progress.</div>
+  </section>
+  <section>
+    <h2>Recent commits</h2>
+    <table>
+      <thead><tr><th>Commit</th><th>Sync</th><th>Workloads</th><th>Release</th><th>Updated</th></tr></thead>
+      <tbody id="commits"></tbody>
+    </table>
+  </section>
+  <section>
+    <h2>Poll history</h2>
+    <table>
+      <thead><tr><th>Time</th><th>Commit</th><th>State</th><th>Message</th></tr></thead>
+      <tbody id="history"></tbody>
+    </table>
+  </section>
+</main>
+<script>
+(function() {
+  var historyLimit = 50;
+  var refreshDelay = 250;
+  var pollCommit = null;
+  var refreshTimer = null;
+  // seeded has the rows read from the history, replayed stream messages matching them are skipped
+  var seeded = {};
+
+  function escape(s) {
+    return String(s === undefined || s === null ? "" : s).replace(/[&<>"']/g, function(c) {
+      return {"&": "&amp;", "<": "&lt;", ">": "&gt;", '"': "&quot;", "'": "&#39;"}[c];
+    });
+  }
+
+  function short(id) {
+    return escape((id || "").substring(0, 7));
+  }
+
+  function time(t) {
+    return t ? escape(new Date(t).toLocaleString()) : "";
+  }
+
+  function badge(status) {
+    if (!status) {
+      return '<span class="muted">-</span>';
+    }
+    return '<span class="state state-' + escape(status.state) + '" title="' + escape(status.message) + '">' + escape(status.state || "unknown") + '</span>';
+  }
+
+  function list(items) {
+    if (!items || items.length === 0) {
+      return "";
+    }
+    return "<ul>" + items.join("") + "</ul>";
+  }
+
+  function errors(status) {
+    if (!status || !status.errors) {
+      return "";
+    }
+    return '<div class="errors">' + list(status.errors.map(function(e) {
+      return "<li><code>" + escape(e.id) + "</code>" + (e.path ? " (" + escape(e.path) + ")" : "") + ": " + escape(e.error) + "</li>";
+    })) + "</div>";
+  }
+
+  function pending(ids) {
+    return list((ids || []).map(function(id) {
+      return "<li><code>" + escape(id) + "</code></li>";
+    }));
+  }
+
+  function renderCurrent(instance) {
+    document.getElementById("instance").textContent = instance.instance;
+    var commit = instance.current;
+    if (!commit) {
+      return;
+    }
+    var html = "<p><code>" + escape(commit.commitId) + "</code> updated " + time(commit.updatedAt) + "</p><table>";
+    ["sync", "workload", "release"].forEach(function(type) {
+      var status = commit.statuses[type];
+      if (!status) {
+        return;
+      }
+      html += "<tr><td>" + type + "</td><td>" + badge(status) + "</td><td>" + escape(status.message) + errors(status) + pending(status.pending) + "</td></tr>";
+    });
+    var current = document.getElementById("current");
+    current.className = "";
+    current.innerHTML = html + "</table>";
+  }
+
+  function renderCommits(commits) {
+    document.getElementById("commits").innerHTML = commits.map(function(c) {
+      return "<tr><td><code>" + short(c.commitId) + "</code></td><td>" + badge(c.statuses.sync) + errors(c.statuses.sync) +
+        "</td><td>" + badge(c.statuses.workload) + errors(c.statuses.workload) + "</td><td>" + badge(c.statuses.release) +
+        "</td><td>" + time(c.updatedAt) + "</td></tr>";
+    }).join("");
+  }
+
+  function renderPoll(m) {
+    var poll = document.getElementById("poll");
+    if (m.type === "workload" && m.state !== "pending" && m.commitId === pollCommit) {
+      pollCommit = null;
+      poll.className = "muted";
+      poll.textContent = "No poll in progress.";
+      return;
+    }
+    if (m.type !== "poll") {
+      return;
+    }
+    pollCommit = m.commitId;
+    poll.className = "";
+    poll.innerHTML = "<p><code>" + short(m.commitId) + "</code> " + escape(m.message) + ' <span class="muted">' + time(m.time) + "</span></p>" + pending(m.pending);
+  }
+
+  function historyKey(m) {
+    return [m.commitId, m.state, m.message].join("/");
+  }
+
+  function historyRow(m) {
+    var row = document.createElement("tr");
+    row.innerHTML = "<td>" + time(m.time) + "</td><td><code>" + short(m.commitId) + "</code></td><td>" + badge(m) + "</td><td>" + escape(m.message) + errors(m) + pending(m.pending) + "</td>";
+    return row;
+  }
+
+  function addHistory(m) {
+    if (m.type !== "workload" || m.state === "pending") {
+      return;
+    }
+    var key = historyKey(m);
+    if (seeded[key] > 0) {
+      seeded[key]--;
+      return;
+    }
+    var history = document.getElementById("history");
+    history.insertBefore(historyRow(m), history.firstChild);
+    while (history.children.length > historyLimit) {
+      history.removeChild(history.lastChild);
+    }
+  }
+
+  function seedHistory(entries) {
+    var history = document.getElementById("history");
+    entries.forEach(function(e) {
+      var key = historyKey(e);
+      seeded[key] = (seeded[key] || 0) + 1;
+      history.appendChild(historyRow(e));
+    });
+  }
+
+  function get(path, render, done) {
+    var req = new XMLHttpRequest();
+    req.open("GET", path);
+    req.onloadend = function() {
+      if (req.status === 200) {
+        render(JSON.parse(req.responseText));
+      }
+      if (done) {
+        done();
+      }
+    };
+    req.send();
+  }
+
+  function refresh() {
+    get("api/v1/instances/current", renderCurrent);
+    get("api/v1/commits", renderCommits);
+  }
+
+  // scheduleRefresh refreshes once after a burst of messages, such as the replay when connecting.
+  function scheduleRefresh() {
+    clearTimeout(refreshTimer);
+    refreshTimer = setTimeout(refresh, refreshDelay);
+  }
+
+  function connect() {
+    var connection = document.getElementById("connection");
+    if (!window.EventSource) {
+      connection.textContent = "Live updates not supported by browser";
+      return;
+    }
+    var source = new EventSource("api/v1/stream");
+    source.onopen = function() {
+      connection.textContent = "Live";
+    };
+    source.onerror = function() {
+      connection.textContent = "Reconnecting...";
+    };
+    ["sync", "workload", "release", "poll"].forEach(function(type) {
+      source.addEventListener(type, function(e) {
+        var m = JSON.parse(e.data);
+        renderPoll(m);
+        addHistory(m);
+        if (type !== "poll") {
+          scheduleRefresh();
+        }
+      });
+    });
+  }
+
+  refresh();
+  // The history is only served when the history store is enabled
+  get("api/v1/history/workloads?limit=" + historyLimit, seedHistory, connect);
+})();
+</script>
+</body>
+</html>
+`
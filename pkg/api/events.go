@@ -5,15 +5,28 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strings"
 
 	"github.com/fluxcd/flux/pkg/event"
+	"github.com/fluxcd/flux/pkg/update"
 	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/label"
 
+	"github.com/xenitab/flux-status/pkg/history"
+	"github.com/xenitab/flux-status/pkg/metrics"
 	"github.com/xenitab/flux-status/pkg/notifier"
+	"github.com/xenitab/flux-status/pkg/tracing"
 )
 
-func eventHandler(log logr.Logger, noti notifier.Notifier, events chan<- string) http.HandlerFunc {
+func eventHandler(log logr.Logger, noti notifier.Notifier, events chan<- notifier.Event, reportIncluded bool, store *history.Store) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := tracing.Start(r.Context(), "event.receive", "")
+		defer span.End()
+		eventType, state := metrics.EventUnknown, metrics.EventRejected
+		defer func() {
+			metrics.EventsReceived.WithLabelValues(eventType, state).Inc()
+		}()
+
 		// Read Flux event
 		body, err := ioutil.ReadAll(r.Body)
 		if err != nil {
@@ -28,54 +41,214 @@ func eventHandler(log logr.Logger, noti notifier.Notifier, events chan<- string)
 			return
 		}
 
-		// Send notifier event
-		event, err := convertToEvent(fluxEvent)
+		eventType = fluxEvent.Type
+		span.SetAttributes(label.String("event.type", fluxEvent.Type))
+
+		// Send notifier events
+		_, convertSpan := tracing.Start(ctx, "event.convert", "")
+		notiEvents, err := convertToEvents(fluxEvent, reportIncluded)
+		tracing.End(ctx, convertSpan, err)
 		if err != nil {
 			log.Error(err, "Could not convert event to notifier event")
 			http.Error(w, err.Error(), 400)
 			return
 		}
-		if err := noti.Send(r.Context(), event); err != nil {
-			log.Error(err, "Could not send event through notifier")
-			http.Error(w, err.Error(), 500)
+		recordEvent(log, store, fluxEvent, notiEvents)
+		if len(notiEvents) == 0 {
+			state = metrics.EventIgnored
+			log.Info("Received event without any commit status", "type", fluxEvent.Type)
+			w.WriteHeader(200)
 			return
 		}
-		log.Info("Sent sync event", "commit-id", event.CommitID)
+		state = string(notiEvents[0].State)
+		span.SetAttributes(tracing.CommitIDKey.String(notiEvents[0].CommitID))
+		for _, e := range notiEvents {
+			if err := noti.Send(ctx, e); err != nil {
+				log.Error(err, "Could not send event through notifier")
+				http.Error(w, err.Error(), 500)
+				return
+			}
+			log.Info("Sent event", "type", e.Type, "commit-id", e.CommitID)
+		}
 
-		// Only send commit Event if it not failed
-		if event.State != notifier.EventStateFailed && events != nil {
-			events <- event.CommitID
+		// Only send the first Event if it has not failed or is still pending
+		first := notiEvents[0]
+		if first.State != notifier.EventStateFailed && first.State != notifier.EventStatePending && events != nil {
+			if first.Type == notifier.EventTypeSync {
+				first.Changed = fluxEvent.ServiceIDs
+			}
+			// The poller is stopped when the leadership is lost, so the request may be done first
+			select {
+			case events <- first:
+			case <-r.Context().Done():
+				log.Info("Could not start polling before the request was done", "commit-id", first.CommitID)
+				http.Error(w, "Poller not available", http.StatusServiceUnavailable)
+				return
+			}
 		}
 
 		w.WriteHeader(200)
 	})
 }
 
-func convertToEvent(e event.Event) (notifier.Event, error) {
-	if e.Metadata.Type() != event.EventSync {
-		return notifier.Event{}, fmt.Errorf("Could not parse event metatada type: %v", e.Metadata.Type())
+// recordEvent records the Flux event in the history if a store is configured.
+func recordEvent(log logr.Logger, store *history.Store, e event.Event, notiEvents []notifier.Event) {
+	if store == nil {
+		return
+	}
+
+	entry := history.Entry{
+		Kind:    history.KindEvent,
+		Type:    e.Type,
+		Message: e.String(),
+	}
+	if len(notiEvents) > 0 {
+		entry.CommitID = notiEvents[0].CommitID
+	}
+	if err := store.Add(entry); err != nil {
+		log.Error(err, "Could not record event in history")
+	}
+}
+
+// convertToEvents returns the notifier events for a Flux event, starting with
+// the event that workloads should be polled for.
+func convertToEvents(e event.Event, reportIncluded bool) ([]notifier.Event, error) {
+	switch metadata := e.Metadata.(type) {
+	case *event.SyncEventMetadata:
+		return convertSyncEvent(metadata, reportIncluded), nil
+	case *event.CommitEventMetadata:
+		return convertCommitEvent(metadata), nil
+	case *event.ReleaseEventMetadata:
+		return convertReleaseEvent(metadata.ReleaseEventCommon), nil
+	case *event.AutoReleaseEventMetadata:
+		return convertReleaseEvent(metadata.ReleaseEventCommon), nil
+	default:
+		return nil, fmt.Errorf("Could not parse event metatada type: %v", e.Type)
 	}
+}
 
-	syncMetadata := e.Metadata.(*event.SyncEventMetadata)
-	commitID := syncMetadata.Commits[0].Revision
+// convertSyncEvent returns the events for all commits in a sync, starting with the head commit.
+// Flux lists the commits newest first so every commit but the first was included in the sync of the head.
+func convertSyncEvent(metadata *event.SyncEventMetadata, reportIncluded bool) []notifier.Event {
+	if len(metadata.Commits) == 0 {
+		return []notifier.Event{}
+	}
+	commitID := metadata.Commits[0].Revision
 
 	var message string
 	var state notifier.EventState
-	if len(syncMetadata.Errors) == 0 {
+	errs := []notifier.ResourceError{}
+	if len(metadata.Errors) == 0 {
 		state = notifier.EventStateSucceeded
 		message = "Succeeded"
 	} else {
 		state = notifier.EventStateFailed
-		message = "Errors:"
-		for _, err := range syncMetadata.Errors {
-			message = message + err.ID.String() + ","
+		message = fmt.Sprintf("Failed applying %d resources", len(metadata.Errors))
+		for _, err := range metadata.Errors {
+			errs = append(errs, notifier.ResourceError{
+				ID:    err.ID,
+				Path:  err.Path,
+				Error: err.Error,
+			})
+		}
+	}
+
+	result := []notifier.Event{
+		{
+			Type:     notifier.EventTypeSync,
+			Message:  message,
+			CommitID: commitID,
+			State:    state,
+			Errors:   errs,
+		},
+	}
+	if !reportIncluded {
+		return result
+	}
+
+	for _, commit := range metadata.Commits[1:] {
+		result = append(result, notifier.Event{
+			Type:       notifier.EventTypeSync,
+			Message:    fmt.Sprintf("Included in sync of %v", shortRevision(commitID)),
+			CommitID:   commit.Revision,
+			State:      state,
+			Errors:     errs,
+			IncludedIn: commitID,
+		})
+	}
+
+	return result
+}
+
+// convertCommitEvent returns a pending release event for image updates committed by Flux.
+// Other commits, such as policy updates, do not get a status.
+func convertCommitEvent(metadata *event.CommitEventMetadata) []notifier.Event {
+	if metadata.Spec == nil {
+		return []notifier.Event{}
+	}
+
+	switch metadata.Spec.Type {
+	case update.Images, update.Auto, update.Containers:
+	default:
+		return []notifier.Event{}
+	}
+
+	return []notifier.Event{
+		{
+			Type:     notifier.EventTypeRelease,
+			Message:  "Committed image update " + strings.Join(metadata.Result.ChangedImages(), ", "),
+			CommitID: metadata.Revision,
+			State:    notifier.EventStatePending,
+		},
+	}
+}
+
+// convertReleaseEvent returns the result of a release applied by Flux.
+func convertReleaseEvent(metadata event.ReleaseEventCommon) []notifier.Event {
+	errs := []notifier.ResourceError{}
+	for id, result := range metadata.Result {
+		if result.Status != update.ReleaseStatusFailed {
+			continue
+		}
+
+		errs = append(errs, notifier.ResourceError{
+			ID:    id,
+			Error: result.Error,
+		})
+	}
+
+	if metadata.Error != "" || len(errs) > 0 {
+		message := "Release failed"
+		if metadata.Error != "" {
+			message = message + ": " + metadata.Error
+		}
+
+		return []notifier.Event{
+			{
+				Type:     notifier.EventTypeRelease,
+				Message:  message,
+				CommitID: metadata.Revision,
+				State:    notifier.EventStateFailed,
+				Errors:   errs,
+			},
 		}
 	}
 
-	return notifier.Event{
-		Type:     notifier.EventTypeSync,
-		Message:  message,
-		CommitID: commitID,
-		State:    state,
-	}, nil
+	return []notifier.Event{
+		{
+			Type:     notifier.EventTypeRelease,
+			Message:  "Released " + strings.Join(metadata.Result.ChangedImages(), ", "),
+			CommitID: metadata.Revision,
+			State:    notifier.EventStateSucceeded,
+		},
+	}
+}
+
+// shortRevision returns the abbreviated form of a commit id.
+func shortRevision(commitID string) string {
+	if len(commitID) > 7 {
+		return commitID[:7]
+	}
+
+	return commitID
 }
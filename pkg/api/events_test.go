@@ -2,6 +2,7 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -9,11 +10,15 @@ import (
 	"time"
 
 	"github.com/fluxcd/flux/pkg/event"
+	"github.com/fluxcd/flux/pkg/image"
 	"github.com/fluxcd/flux/pkg/resource"
+	"github.com/fluxcd/flux/pkg/update"
 	logr "github.com/go-logr/logr/testing"
 	"github.com/onsi/gomega"
 	"github.com/onsi/gomega/gstruct"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 
+	"github.com/xenitab/flux-status/pkg/metrics"
 	"github.com/xenitab/flux-status/pkg/notifier"
 )
 
@@ -22,13 +27,13 @@ func TestEnabledPoller(t *testing.T) {
 
 	log := logr.TestLogger{T: t}
 	noti := notifier.NewMock()
-	events := make(chan string, 1)
+	events := make(chan notifier.Event, 1)
 
 	commitID := "foobar"
 	fluxEvent := event.Event{
 		ID:         1,
 		Type:       "sync",
-		ServiceIDs: []resource.ID{},
+		ServiceIDs: []resource.ID{resource.MustParseID("default:deployment/app")},
 		LogLevel:   "info",
 		Message:    "",
 		StartedAt:  time.Now(),
@@ -48,10 +53,14 @@ func TestEnabledPoller(t *testing.T) {
 	req, err := http.NewRequest("GET", "/v6/events", bytes.NewReader(body))
 	g.Expect(err).ShouldNot(gomega.HaveOccurred())
 	rr := httptest.NewRecorder()
-	eventHandler(log, noti, events).ServeHTTP(rr, req)
+	eventHandler(log, noti, events, true, nil).ServeHTTP(rr, req)
 	g.Expect(rr.Code).Should(gomega.Equal(http.StatusOK))
 
-	g.Expect(events).Should(gomega.Receive(gomega.Equal(commitID)))
+	g.Expect(events).Should(gomega.Receive(gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
+		"Type":     gomega.Equal(notifier.EventTypeSync),
+		"CommitID": gomega.Equal(commitID),
+		"Changed":  gomega.ConsistOf(resource.MustParseID("default:deployment/app")),
+	})))
 	g.Expect(noti.Events).Should(gomega.Receive(gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
 		"Type":     gomega.Equal(notifier.EventTypeSync),
 		"CommitID": gomega.Equal(commitID),
@@ -61,6 +70,33 @@ func TestEnabledPoller(t *testing.T) {
 	g.Consistently(noti.Events).ShouldNot(gomega.Receive())
 }
 
+func TestStoppedPoller(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	// Nothing receives the events when the poller has been stopped
+	events := make(chan notifier.Event)
+	fluxEvent := event.Event{
+		ID:        1,
+		Type:      "sync",
+		LogLevel:  "info",
+		StartedAt: time.Now(),
+		EndedAt:   time.Now(),
+		Metadata: &event.SyncEventMetadata{
+			Commits: []event.Commit{{Revision: "foobar"}},
+		},
+	}
+	body, err := json.Marshal(fluxEvent)
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", "/v6/events", bytes.NewReader(body))
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	rr := httptest.NewRecorder()
+	eventHandler(logr.TestLogger{T: t}, notifier.NewMock(), events, true, nil).ServeHTTP(rr, req)
+	g.Expect(rr.Code).Should(gomega.Equal(http.StatusServiceUnavailable))
+}
+
 func TestDisabledPoller(t *testing.T) {
 	g := gomega.NewGomegaWithT(t)
 
@@ -91,7 +127,306 @@ func TestDisabledPoller(t *testing.T) {
 		req, err := http.NewRequest("GET", "/v6/events", bytes.NewReader(body))
 		g.Expect(err).ShouldNot(gomega.HaveOccurred())
 		rr := httptest.NewRecorder()
-		eventHandler(log, noti, nil).ServeHTTP(rr, req)
+		eventHandler(log, noti, nil, true, nil).ServeHTTP(rr, req)
 		g.Expect(rr.Code).Should(gomega.Equal(http.StatusOK))
 	}
 }
+
+func TestFailedSync(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	id := resource.MustParseID("namespace:deployment/resource-name")
+	fluxEvent := event.Event{
+		ID:         1,
+		Type:       "sync",
+		ServiceIDs: []resource.ID{},
+		LogLevel:   "info",
+		StartedAt:  time.Now(),
+		EndedAt:    time.Now(),
+		Metadata: &event.SyncEventMetadata{
+			Commits: []event.Commit{
+				{
+					Revision: "foobar",
+				},
+			},
+			Errors: []event.ResourceError{
+				{
+					ID:    id,
+					Path:  "deployment.yaml",
+					Error: "invalid manifest",
+				},
+			},
+		},
+	}
+
+	ee, err := convertToEvents(fluxEvent, true)
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(ee).Should(gomega.HaveLen(1))
+	e := ee[0]
+	g.Expect(e.State).Should(gomega.Equal(notifier.EventStateFailed))
+	g.Expect(e.Message).Should(gomega.Equal("Failed applying 1 resources"))
+	g.Expect(e.Errors).Should(gomega.ConsistOf(notifier.ResourceError{
+		ID:    id,
+		Path:  "deployment.yaml",
+		Error: "invalid manifest",
+	}))
+}
+
+func TestFailedSyncMultipleCommits(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	id := resource.MustParseID("namespace:deployment/resource-name")
+	fluxEvent := event.Event{
+		ID:         1,
+		Type:       "sync",
+		ServiceIDs: []resource.ID{},
+		LogLevel:   "info",
+		StartedAt:  time.Now(),
+		EndedAt:    time.Now(),
+		Metadata: &event.SyncEventMetadata{
+			Commits: []event.Commit{
+				{
+					Revision: "1234567890",
+				},
+				{
+					Revision: "foobar",
+				},
+			},
+			Errors: []event.ResourceError{
+				{
+					ID:    id,
+					Path:  "deployment.yaml",
+					Error: "invalid manifest",
+				},
+			},
+		},
+	}
+
+	ee, err := convertToEvents(fluxEvent, true)
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(ee).Should(gomega.HaveLen(2))
+	e := ee[1]
+	g.Expect(e.CommitID).Should(gomega.Equal("foobar"))
+	g.Expect(e.State).Should(gomega.Equal(notifier.EventStateFailed))
+	g.Expect(e.IncludedIn).Should(gomega.Equal("1234567890"))
+	g.Expect(e.Errors).Should(gomega.Equal(ee[0].Errors))
+}
+
+func TestMultipleCommits(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	log := logr.TestLogger{T: t}
+	noti := notifier.NewMock()
+	events := make(chan notifier.Event, 1)
+
+	fluxEvent := event.Event{
+		ID:         1,
+		Type:       "sync",
+		ServiceIDs: []resource.ID{},
+		LogLevel:   "info",
+		StartedAt:  time.Now(),
+		EndedAt:    time.Now(),
+		Metadata: &event.SyncEventMetadata{
+			Commits: []event.Commit{
+				{
+					Revision: "1234567890",
+				},
+				{
+					Revision: "foobar",
+				},
+			},
+		},
+	}
+	body, err := json.Marshal(fluxEvent)
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+
+	req, err := http.NewRequest("GET", "/v6/events", bytes.NewReader(body))
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	rr := httptest.NewRecorder()
+	eventHandler(log, noti, events, true, nil).ServeHTTP(rr, req)
+	g.Expect(rr.Code).Should(gomega.Equal(http.StatusOK))
+
+	g.Expect(events).Should(gomega.Receive(gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
+		"Type":     gomega.Equal(notifier.EventTypeSync),
+		"CommitID": gomega.Equal("1234567890"),
+	})))
+	g.Expect(noti.Events).Should(gomega.Receive(gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
+		"CommitID": gomega.Equal("1234567890"),
+		"State":    gomega.Equal(notifier.EventStateSucceeded),
+		"Message":  gomega.Equal("Succeeded"),
+	})))
+	g.Expect(noti.Events).Should(gomega.Receive(gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
+		"CommitID": gomega.Equal("foobar"),
+		"State":    gomega.Equal(notifier.EventStateSucceeded),
+		"Message":  gomega.Equal("Included in sync of 1234567"),
+	})))
+	g.Consistently(events).ShouldNot(gomega.Receive())
+	g.Consistently(noti.Events).ShouldNot(gomega.Receive())
+}
+
+func TestEmptySync(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	log := logr.TestLogger{T: t}
+	noti := notifier.NewMock()
+	events := make(chan notifier.Event, 1)
+
+	fluxEvent := event.Event{
+		ID:        1,
+		Type:      "sync",
+		LogLevel:  "info",
+		StartedAt: time.Now(),
+		EndedAt:   time.Now(),
+		Metadata: &event.SyncEventMetadata{
+			Commits: []event.Commit{},
+		},
+	}
+	body, err := json.Marshal(fluxEvent)
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+
+	req, err := http.NewRequest("GET", "/v6/events", bytes.NewReader(body))
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	rr := httptest.NewRecorder()
+	eventHandler(log, noti, events, true, nil).ServeHTTP(rr, req)
+	g.Expect(rr.Code).Should(gomega.Equal(http.StatusOK))
+	g.Expect(events).ShouldNot(gomega.Receive())
+	g.Expect(noti.Events).ShouldNot(gomega.Receive())
+}
+
+func TestEventMetrics(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	log := logr.TestLogger{T: t}
+	noti := notifier.NewMock()
+	handler := eventHandler(log, noti, nil, true, nil)
+
+	// The counters are global so only the change is compared
+	rejected := metrics.EventsReceived.WithLabelValues(metrics.EventUnknown, metrics.EventRejected)
+	before := testutil.ToFloat64(rejected)
+	req, err := http.NewRequest("GET", "/v6/events", bytes.NewReader([]byte("{")))
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	g.Expect(rr.Code).Should(gomega.Equal(http.StatusBadRequest))
+	g.Expect(testutil.ToFloat64(rejected) - before).Should(gomega.Equal(1.0))
+
+	// A sync of multiple commits is a single event
+	fluxEvent := event.Event{
+		ID:        1,
+		Type:      "sync",
+		LogLevel:  "info",
+		StartedAt: time.Now(),
+		EndedAt:   time.Now(),
+		Metadata: &event.SyncEventMetadata{
+			Commits: []event.Commit{{Revision: "1234567890"}, {Revision: "foobar"}},
+		},
+	}
+	body, err := json.Marshal(fluxEvent)
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	succeeded := metrics.EventsReceived.WithLabelValues("sync", string(notifier.EventStateSucceeded))
+	before = testutil.ToFloat64(succeeded)
+	req, err = http.NewRequest("GET", "/v6/events", bytes.NewReader(body))
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	g.Expect(rr.Code).Should(gomega.Equal(http.StatusOK))
+	g.Expect(testutil.ToFloat64(succeeded) - before).Should(gomega.Equal(1.0))
+}
+
+func TestCommitEvent(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	id := resource.MustParseID("namespace:deployment/app")
+	fluxEvent := event.Event{
+		ID:        1,
+		Type:      event.EventCommit,
+		LogLevel:  "info",
+		StartedAt: time.Now(),
+		EndedAt:   time.Now(),
+		Metadata: &event.CommitEventMetadata{
+			Revision: "foobar",
+			Spec: &update.Spec{
+				Type: update.Auto,
+			},
+			Result: update.Result{
+				id: update.WorkloadResult{
+					Status: update.ReleaseStatusSuccess,
+					PerContainer: []update.ContainerUpdate{
+						{
+							Container: "app",
+							Target:    image.Ref{Name: image.Name{Image: "app"}, Tag: "v2"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	ee, err := convertToEvents(fluxEvent, true)
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(ee).Should(gomega.ConsistOf(gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
+		"Type":     gomega.Equal(notifier.EventTypeRelease),
+		"CommitID": gomega.Equal("foobar"),
+		"State":    gomega.Equal(notifier.EventStatePending),
+		"Message":  gomega.Equal("Committed image update app:v2"),
+	})))
+}
+
+func TestFailedReleaseEvent(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	id := resource.MustParseID("namespace:deployment/app")
+	fluxEvent := event.Event{
+		ID:        1,
+		Type:      event.EventAutoRelease,
+		LogLevel:  "info",
+		StartedAt: time.Now(),
+		EndedAt:   time.Now(),
+		Metadata: &event.AutoReleaseEventMetadata{
+			ReleaseEventCommon: event.ReleaseEventCommon{
+				Revision: "foobar",
+				Result: update.Result{
+					id: update.WorkloadResult{
+						Status: update.ReleaseStatusFailed,
+						Error:  "not found",
+					},
+				},
+			},
+		},
+	}
+	body, err := json.Marshal(fluxEvent)
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+
+	log := logr.TestLogger{T: t}
+	noti := notifier.NewMock()
+	events := make(chan notifier.Event, 1)
+	req, err := http.NewRequest("GET", "/v6/events", bytes.NewReader(body))
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	rr := httptest.NewRecorder()
+	eventHandler(log, noti, events, true, nil).ServeHTTP(rr, req)
+	g.Expect(rr.Code).Should(gomega.Equal(http.StatusOK))
+
+	g.Expect(noti.Events).Should(gomega.Receive(gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
+		"Type":     gomega.Equal(notifier.EventTypeRelease),
+		"CommitID": gomega.Equal("foobar"),
+		"State":    gomega.Equal(notifier.EventStateFailed),
+		"Errors": gomega.ConsistOf(notifier.ResourceError{
+			ID:    id,
+			Error: "not found",
+		}),
+	})))
+	g.Expect(events).ShouldNot(gomega.Receive())
+}
+
+func TestUnsupportedEvent(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	log := logr.TestLogger{T: t}
+	noti := notifier.NewMock()
+	body := []byte(`{"type":"lock","metadata":{}}`)
+	req, err := http.NewRequest("GET", "/v6/events", bytes.NewReader(body))
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	rr := httptest.NewRecorder()
+	eventHandler(log, noti, nil, true, nil).ServeHTTP(rr, req)
+	g.Expect(rr.Code).Should(gomega.Equal(http.StatusBadRequest))
+}
@@ -0,0 +1,42 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+
+	"github.com/go-logr/logr"
+)
+
+// ForwardedHeader is set on requests forwarded to the leader to prevent forwarding loops.
+const ForwardedHeader = "X-Flux-Status-Forwarded"
+
+// leadership reports which replica is the leader when running multiple replicas.
+type leadership interface {
+	IsLeader() bool
+	Leader() string
+}
+
+// forwardHandler handles the request if this replica is the leader and forwards it to
+// the leader otherwise. The request is forwarded as is, so it is authenticated by the
+// leader with the same token or signature.
+func forwardHandler(log logr.Logger, l leadership, next http.Handler) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if l.IsLeader() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		leader := l.Leader()
+		if leader == "" || r.Header.Get(ForwardedHeader) != "" {
+			log.Info("Could not forward request to leader", "url", r.URL, "leader", leader)
+			http.Error(w, "No leader available", http.StatusServiceUnavailable)
+			return
+		}
+
+		log.Info("Forwarding request to leader", "url", r.URL, "leader", leader)
+		proxy := httputil.NewSingleHostReverseProxy(&url.URL{Scheme: "http", Host: leader})
+		r.Header.Set(ForwardedHeader, "true")
+		proxy.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	logr "github.com/go-logr/logr/testing"
+	"github.com/onsi/gomega"
+)
+
+type mockLeadership struct {
+	leader   bool
+	identity string
+}
+
+func (m mockLeadership) IsLeader() bool {
+	return m.leader
+}
+
+func (m mockLeadership) Leader() string {
+	return m.identity
+}
+
+func TestForwardHandler(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	handled := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Write([]byte(r.Header.Get(ForwardedHeader) + ":" + string(body)))
+	})
+	leader := httptest.NewServer(forwardHandler(logr.TestLogger{T: t}, mockLeadership{leader: true}, handled))
+	defer leader.Close()
+	follower := httptest.NewServer(forwardHandler(logr.TestLogger{T: t}, mockLeadership{identity: strings.TrimPrefix(leader.URL, "http://")}, handled))
+	defer follower.Close()
+	orphan := httptest.NewServer(forwardHandler(logr.TestLogger{T: t}, mockLeadership{}, handled))
+	defer orphan.Close()
+
+	resp, err := http.Post(leader.URL+"/v6/events", "application/json", strings.NewReader("foo"))
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	g.Expect(string(body)).Should(gomega.Equal(":foo"))
+
+	resp, err = http.Post(follower.URL+"/v6/events", "application/json", strings.NewReader("foo"))
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	body, _ = ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	g.Expect(string(body)).Should(gomega.Equal("true:foo"))
+
+	resp, err = http.Post(orphan.URL+"/v6/events", "application/json", strings.NewReader("foo"))
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	resp.Body.Close()
+	g.Expect(resp.StatusCode).Should(gomega.Equal(http.StatusServiceUnavailable))
+
+	req, _ := http.NewRequest("POST", follower.URL+"/v6/events", strings.NewReader("foo"))
+	req.Header.Set(ForwardedHeader, "true")
+	resp, err = http.DefaultClient.Do(req)
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	resp.Body.Close()
+	g.Expect(resp.StatusCode).Should(gomega.Equal(http.StatusServiceUnavailable))
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// checkTimeout is the max duration of a single readiness check.
+const checkTimeout = 5 * time.Second
+
+// These constants represents the status of a health check.
+const (
+	healthOK     = "ok"
+	healthFailed = "failed"
+)
+
+// healthCheck verifies that a dependency is available.
+type healthCheck func(context.Context) error
+
+type checkResult struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type healthResult struct {
+	Status string                 `json:"status"`
+	Checks map[string]checkResult `json:"checks"`
+}
+
+// healthHandler runs all checks and responds with the result of each check. The
+// response status is 503 if any of the checks fail.
+func healthHandler(log logr.Logger, checks map[string]healthCheck) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		result := healthResult{
+			Status: healthOK,
+			Checks: map[string]checkResult{},
+		}
+		for name, check := range checks {
+			ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+			err := check(ctx)
+			cancel()
+			if err != nil {
+				log.Info("Health check failed", "check", name, "error", err.Error())
+				result.Status = healthFailed
+				result.Checks[name] = checkResult{Status: healthFailed, Error: err.Error()}
+				continue
+			}
+			result.Checks[name] = checkResult{Status: healthOK}
+		}
+
+		b, err := json.Marshal(result)
+		if err != nil {
+			log.Error(err, "Could not marshal json")
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if result.Status != healthOK {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		if _, err := w.Write(b); err != nil {
+			log.Error(err, "Could not write response")
+		}
+	})
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	logr "github.com/go-logr/logr/testing"
+	"github.com/onsi/gomega"
+
+	"github.com/xenitab/flux-status/pkg/flux"
+	"github.com/xenitab/flux-status/pkg/notifier"
+)
+
+func TestHealthHandler(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	req := httptest.NewRequest("GET", "/healthz", nil)
+	rr := httptest.NewRecorder()
+	healthHandler(logr.TestLogger{T: t}, map[string]healthCheck{}).ServeHTTP(rr, req)
+	g.Expect(rr.Code).Should(gomega.Equal(http.StatusOK))
+	g.Expect(rr.Body.String()).Should(gomega.MatchJSON(`{"status":"ok","checks":{}}`))
+}
+
+func TestReadinessChecks(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	noti := notifier.NewMock()
+	s := NewServer(noti, nil, logr.TestLogger{T: t}, false, nil, "", "")
+	s.Flux = flux.NewUpstream()
+
+	req := httptest.NewRequest("GET", "/readyz", nil)
+	rr := httptest.NewRecorder()
+	healthHandler(logr.TestLogger{T: t}, s.readinessChecks()).ServeHTTP(rr, req)
+	g.Expect(rr.Code).Should(gomega.Equal(http.StatusServiceUnavailable))
+	g.Expect(rr.Body.String()).Should(gomega.MatchJSON(`{"status":"failed","checks":{"notifier":{"status":"ok"},"flux":{"status":"failed","error":"` + flux.ErrNotConnected.Error() + `"}}}`))
+
+	s.Flux = &flux.Mock{}
+	noti.AuthErr = errors.New("bad credentials")
+	rr = httptest.NewRecorder()
+	healthHandler(logr.TestLogger{T: t}, s.readinessChecks()).ServeHTTP(rr, req)
+	g.Expect(rr.Code).Should(gomega.Equal(http.StatusServiceUnavailable))
+	g.Expect(rr.Body.String()).Should(gomega.MatchJSON(`{"status":"failed","checks":{"notifier":{"status":"failed","error":"bad credentials"},"flux":{"status":"ok"}}}`))
+
+	noti.AuthErr = nil
+	rr = httptest.NewRecorder()
+	healthHandler(logr.TestLogger{T: t}, s.readinessChecks()).ServeHTTP(rr, req)
+	g.Expect(rr.Code).Should(gomega.Equal(http.StatusOK))
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/gorilla/mux"
+
+	"github.com/xenitab/flux-status/pkg/history"
+)
+
+// defaultDeploymentsLimit is the amount of deployments returned if no limit is requested.
+const defaultDeploymentsLimit = 20
+
+// deploymentsHandler returns the most recent deployments, or the deployments
+// between the from and to query parameters if they are set.
+func deploymentsHandler(log logr.Logger, store *history.Store) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("from") != "" || query.Get("to") != "" {
+			from, err := parseTime(query.Get("from"), time.Time{})
+			if err != nil {
+				http.Error(w, "Invalid from time", 400)
+				return
+			}
+			to, err := parseTime(query.Get("to"), time.Now())
+			if err != nil {
+				http.Error(w, "Invalid to time", 400)
+				return
+			}
+
+			entries, err := store.DeploymentsBetween(from, to)
+			if err != nil {
+				log.Error(err, "Could not read history")
+				http.Error(w, err.Error(), 500)
+				return
+			}
+			writeJSON(log, w, entries)
+			return
+		}
+
+		limit, err := parseLimit(query.Get("limit"), defaultDeploymentsLimit)
+		if err != nil {
+			http.Error(w, "Invalid limit", 400)
+			return
+		}
+
+		entries, err := store.Deployments(limit)
+		if err != nil {
+			log.Error(err, "Could not read history")
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		writeJSON(log, w, entries)
+	})
+}
+
+// workloadsHandler returns the most recent final workload statuses.
+func workloadsHandler(log logr.Logger, store *history.Store) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit, err := parseLimit(r.URL.Query().Get("limit"), defaultDeploymentsLimit)
+		if err != nil {
+			http.Error(w, "Invalid limit", 400)
+			return
+		}
+
+		entries, err := store.Workloads(limit)
+		if err != nil {
+			log.Error(err, "Could not read history")
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		writeJSON(log, w, entries)
+	})
+}
+
+func commitHistoryHandler(log logr.Logger, store *history.Store) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entries, err := store.Commit(mux.Vars(r)["sha"])
+		if err != nil {
+			log.Error(err, "Could not read history")
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		if len(entries) == 0 {
+			http.Error(w, "Commit not found", 404)
+			return
+		}
+
+		writeJSON(log, w, entries)
+	})
+}
+
+// parseTime parses the RFC3339 formatted time or returns the default if empty.
+func parseTime(s string, def time.Time) (time.Time, error) {
+	if s == "" {
+		return def, nil
+	}
+
+	return time.Parse(time.RFC3339, s)
+}
+
+// parseLimit parses the positive limit or returns the default if empty.
+func parseLimit(s string, def int) (int, error) {
+	if s == "" {
+		return def, nil
+	}
+
+	limit, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, err
+	}
+	if limit < 1 {
+		return 0, fmt.Errorf("Limit must be positive: %d", limit)
+	}
+
+	return limit, nil
+}
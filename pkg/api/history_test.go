@@ -0,0 +1,64 @@
+package api
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	logr "github.com/go-logr/logr/testing"
+	"github.com/gorilla/mux"
+	"github.com/onsi/gomega"
+
+	"github.com/xenitab/flux-status/pkg/history"
+)
+
+func TestHistoryHandlers(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	dir, err := ioutil.TempDir("", "history")
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	defer os.RemoveAll(dir)
+	store, err := history.Open(filepath.Join(dir, "history.db"), "dev", 0, 0)
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	defer store.Close()
+	g.Expect(store.Add(history.Entry{Kind: history.KindStatus, Type: "sync", CommitID: "foo"})).Should(gomega.Succeed())
+	g.Expect(store.Add(history.Entry{Kind: history.KindStatus, Type: "sync", CommitID: "bar"})).Should(gomega.Succeed())
+	g.Expect(store.Add(history.Entry{Kind: history.KindStatus, Type: "workload", CommitID: "bar", State: "failed"})).Should(gomega.Succeed())
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/history/deployments", deploymentsHandler(logr.TestLogger{T: t}, store))
+	router.HandleFunc("/api/v1/history/workloads", workloadsHandler(logr.TestLogger{T: t}, store))
+	router.HandleFunc("/api/v1/history/commits/{sha}", commitHistoryHandler(logr.TestLogger{T: t}, store))
+
+	cases := []struct {
+		url     string
+		code    int
+		entries int
+	}{
+		{url: "/api/v1/history/deployments", code: 200, entries: 2},
+		{url: "/api/v1/history/deployments?limit=1", code: 200, entries: 1},
+		{url: "/api/v1/history/deployments?limit=foo", code: 400},
+		{url: "/api/v1/history/deployments?from=2000-01-01T00:00:00Z", code: 200, entries: 2},
+		{url: "/api/v1/history/deployments?to=2000-01-01T00:00:00Z", code: 200, entries: 0},
+		{url: "/api/v1/history/deployments?from=foo", code: 400},
+		{url: "/api/v1/history/workloads", code: 200, entries: 1},
+		{url: "/api/v1/history/workloads?limit=0", code: 400},
+		{url: "/api/v1/history/commits/foo", code: 200, entries: 1},
+		{url: "/api/v1/history/commits/baz", code: 404},
+	}
+	for _, c := range cases {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("GET", c.url, nil))
+		g.Expect(rr.Code).Should(gomega.Equal(c.code), c.url)
+		if c.code != http.StatusOK {
+			continue
+		}
+		entries := []history.Entry{}
+		g.Expect(json.Unmarshal(rr.Body.Bytes(), &entries)).Should(gomega.Succeed())
+		g.Expect(entries).Should(gomega.HaveLen(c.entries), c.url)
+	}
+}
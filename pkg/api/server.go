@@ -6,32 +6,98 @@ import (
 
 	"github.com/go-logr/logr"
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 
+	"github.com/xenitab/flux-status/pkg/flux"
+	"github.com/xenitab/flux-status/pkg/history"
+	"github.com/xenitab/flux-status/pkg/leader"
 	"github.com/xenitab/flux-status/pkg/notifier"
+	"github.com/xenitab/flux-status/pkg/state"
+	"github.com/xenitab/flux-status/pkg/stream"
 )
 
-// Server implements the api endpoints to receive events sent by Flux.
+// Server implements the api endpoints to receive events and the upstream connection from Flux.
+// The endpoints used by Flux are configured when creating the server, the optional features
+// below are enabled by setting their fields before starting it.
 type Server struct {
-	Notifier   notifier.Notifier
-	Events     chan<- string
-	Log        logr.Logger
+	Notifier       notifier.Notifier
+	Events         chan<- notifier.Event
+	Log            logr.Logger
+	ReportIncluded bool
+	Upstream       *flux.Upstream
+	Token          string
+	SignatureKey   string
+
+	// Flux is checked by the readiness probe if set
+	Flux flux.Client
+	// State serves the commit state and the dashboard if set
+	State *state.State
+	// Broker streams statuses if set
+	Broker *stream.Broker
+	// History serves the recorded history if set
+	History *history.Store
+	// Elector forwards events to the leader if set
+	Elector *leader.Elector
+
 	httpServer *http.Server
 }
 
-// NewServer creates and returns a Server instance.
-func NewServer(n notifier.Notifier, e chan<- string, l logr.Logger) *Server {
+// NewServer creates and returns a Server instance. Requests from Flux are not authenticated
+// if both the token and the signature key are empty.
+func NewServer(n notifier.Notifier, e chan<- notifier.Event, l logr.Logger, ri bool, u *flux.Upstream, token string, signatureKey string) *Server {
 	return &Server{
-		Notifier: n,
-		Events:   e,
-		Log:      l,
+		Notifier:       n,
+		Events:         e,
+		Log:            l,
+		ReportIncluded: ri,
+		Upstream:       u,
+		Token:          token,
+		SignatureKey:   signatureKey,
 	}
 }
 
 // Start starts serving the api server.
 func (s *Server) Start(addr string) error {
 	router := mux.NewRouter()
-	router.HandleFunc("/v6/events", eventHandler(s.Log, s.Notifier, s.Events))
-	router.PathPrefix("/").HandlerFunc(websocketHandler(s.Log))
+
+	// Endpoints used by Flux
+	fluxRouter := router.NewRoute().Subrouter()
+	fluxRouter.Use(authMiddleware(s.Log, s.Token, s.SignatureKey))
+	var events http.Handler = eventHandler(s.Log, s.Notifier, s.Events, s.ReportIncluded, s.History)
+	if s.Elector != nil {
+		// Only the leader sends statuses, followers forward the events to it
+		events = forwardHandler(s.Log, s.Elector, events)
+	}
+	fluxRouter.Handle("/v6/events", events)
+	fluxRouter.HandleFunc("/v11/daemon", websocketHandler(s.Log, s.Upstream))
+
+	// Status query endpoints
+	apiRouter := router.PathPrefix("/api/v1").Methods("GET").Subrouter()
+	if s.State != nil {
+		apiRouter.HandleFunc("/commits", commitsHandler(s.Log, s.State))
+		apiRouter.HandleFunc("/commits/{sha}", commitHandler(s.Log, s.State))
+		apiRouter.HandleFunc("/instances/current", instanceHandler(s.Log, s.State))
+	}
+	if s.History != nil {
+		apiRouter.HandleFunc("/history/deployments", deploymentsHandler(s.Log, s.History))
+		apiRouter.HandleFunc("/history/workloads", workloadsHandler(s.Log, s.History))
+		apiRouter.HandleFunc("/history/commits/{sha}", commitHistoryHandler(s.Log, s.History))
+	}
+	if s.Broker != nil {
+		apiRouter.HandleFunc("/stream", streamHandler(s.Log, s.Broker))
+	}
+
+	// Probes
+	router.Methods("GET").Path("/healthz").Handler(healthHandler(s.Log, map[string]healthCheck{}))
+	router.Methods("GET").Path("/readyz").Handler(healthHandler(s.Log, s.readinessChecks()))
+
+	// Metrics
+	router.Methods("GET").Path("/metrics").Handler(promhttp.Handler())
+
+	// Dashboard
+	if s.State != nil {
+		router.Methods("GET").Path("/").Handler(dashboardHandler(s.Log))
+	}
 
 	s.httpServer = &http.Server{
 		Addr:    addr,
@@ -41,7 +107,27 @@ func (s *Server) Start(addr string) error {
 	return s.httpServer.ListenAndServe() // blocking
 }
 
+// readinessChecks returns the checks of the services required to report statuses.
+func (s *Server) readinessChecks() map[string]healthCheck {
+	checks := map[string]healthCheck{
+		"notifier": s.Notifier.Authenticate,
+	}
+	if s.Flux != nil {
+		checks["flux"] = func(ctx context.Context) error {
+			_, err := s.Flux.ListServices(ctx, "")
+			return err
+		}
+	}
+
+	return checks
+}
+
 // Stop gracefully stops serving the api server.
 func (s *Server) Stop(ctx context.Context) error {
+	// Streams would otherwise keep the server from shutting down
+	if s.Broker != nil {
+		s.Broker.Close()
+	}
+
 	return s.httpServer.Shutdown(ctx)
 }
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-logr/logr"
+	"github.com/gorilla/mux"
+
+	"github.com/xenitab/flux-status/pkg/state"
+)
+
+func commitsHandler(log logr.Logger, s *state.State) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(log, w, s.Commits())
+	})
+}
+
+func commitHandler(log logr.Logger, s *state.State) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		commitID := mux.Vars(r)["sha"]
+		commit, ok := s.Commit(commitID)
+		if !ok {
+			http.Error(w, "Commit not found", 404)
+			return
+		}
+
+		writeJSON(log, w, commit)
+	})
+}
+
+func instanceHandler(log logr.Logger, s *state.State) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(log, w, s.Instance())
+	})
+}
+
+func writeJSON(log logr.Logger, w http.ResponseWriter, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		log.Error(err, "Could not marshal json")
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(b); err != nil {
+		log.Error(err, "Could not write response")
+	}
+}
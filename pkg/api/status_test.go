@@ -0,0 +1,76 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	logr "github.com/go-logr/logr/testing"
+	"github.com/gorilla/mux"
+	"github.com/onsi/gomega"
+
+	"github.com/xenitab/flux-status/pkg/notifier"
+	"github.com/xenitab/flux-status/pkg/state"
+)
+
+func TestCommitHandler(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	s := state.NewState("dev", 10)
+	s.Record(notifier.Event{Type: notifier.EventTypeSync, CommitID: "foobar", State: notifier.EventStateSucceeded, Message: "Succeeded"})
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/commits/{sha}", commitHandler(logr.TestLogger{T: t}, s))
+
+	req := httptest.NewRequest("GET", "/api/v1/commits/foobar", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	g.Expect(rr.Code).Should(gomega.Equal(http.StatusOK))
+	commit := state.Commit{}
+	g.Expect(json.Unmarshal(rr.Body.Bytes(), &commit)).Should(gomega.Succeed())
+	g.Expect(commit.CommitID).Should(gomega.Equal("foobar"))
+	g.Expect(commit.Statuses[notifier.EventTypeSync].Message).Should(gomega.Equal("Succeeded"))
+
+	req = httptest.NewRequest("GET", "/api/v1/commits/barfoo", nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	g.Expect(rr.Code).Should(gomega.Equal(http.StatusNotFound))
+}
+
+func TestInstanceHandler(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	s := state.NewState("dev", 10)
+	req := httptest.NewRequest("GET", "/api/v1/instances/current", nil)
+	rr := httptest.NewRecorder()
+	instanceHandler(logr.TestLogger{T: t}, s).ServeHTTP(rr, req)
+	g.Expect(rr.Code).Should(gomega.Equal(http.StatusOK))
+	g.Expect(rr.Body.String()).Should(gomega.MatchJSON(`{"instance":"dev","current":null}`))
+}
+
+func TestCommitsHandler(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	s := state.NewState("dev", 10)
+	s.Record(notifier.Event{Type: notifier.EventTypeSync, CommitID: "foo"})
+	s.Record(notifier.Event{Type: notifier.EventTypeSync, CommitID: "bar"})
+	req := httptest.NewRequest("GET", "/api/v1/commits", nil)
+	rr := httptest.NewRecorder()
+	commitsHandler(logr.TestLogger{T: t}, s).ServeHTTP(rr, req)
+	g.Expect(rr.Code).Should(gomega.Equal(http.StatusOK))
+	commits := []state.Commit{}
+	g.Expect(json.Unmarshal(rr.Body.Bytes(), &commits)).Should(gomega.Succeed())
+	g.Expect(commits).Should(gomega.HaveLen(2))
+	g.Expect(commits[0].CommitID).Should(gomega.Equal("bar"))
+}
+
+func TestDashboardHandler(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	rr := httptest.NewRecorder()
+	dashboardHandler(logr.TestLogger{T: t}).ServeHTTP(rr, req)
+	g.Expect(rr.Code).Should(gomega.Equal(http.StatusOK))
+	g.Expect(rr.Header().Get("Content-Type")).Should(gomega.HavePrefix("text/html"))
+	g.Expect(rr.Body.String()).ShouldNot(gomega.MatchRegexp(`(src|href)="(https?:)?//`))
+}
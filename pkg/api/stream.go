@@ -0,0 +1,145 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"github.com/xenitab/flux-status/pkg/stream"
+)
+
+// keepAliveInterval is the duration between each comment sent to keep idle streams open.
+const keepAliveInterval = 15 * time.Second
+
+// streamHandler streams status transitions as Server-Sent Events. The stream can be
+// filtered with the commit and type query parameters, and messages missed since the
+// Last-Event-ID header are replayed from the broker buffer. Event ids are prefixed with
+// the broker epoch, an id from another epoch replays the whole buffer.
+func streamHandler(log logr.Logger, b *stream.Broker) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "Streaming not supported", 500)
+			return
+		}
+
+		lastEventID := r.Header.Get("Last-Event-ID")
+		if lastEventID == "" {
+			lastEventID = r.URL.Query().Get("lastEventId")
+		}
+		var lastID uint64
+		if lastEventID != "" {
+			epoch, id, err := parseEventID(lastEventID)
+			if err != nil {
+				http.Error(w, "Invalid Last-Event-ID", 400)
+				return
+			}
+			if epoch == b.Epoch() {
+				lastID = id
+			}
+		}
+		match := streamFilter(r.URL.Query().Get("commit"), r.URL.Query().Get("type"))
+
+		replay, sub := b.Subscribe(lastID)
+		defer b.Unsubscribe(sub)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(200)
+
+		for _, m := range replay {
+			if !match(m) {
+				continue
+			}
+			if err := writeMessage(w, b.Epoch(), m); err != nil {
+				log.Error(err, "Could not write message")
+				return
+			}
+		}
+		flusher.Flush()
+
+		ticker := time.NewTicker(keepAliveInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-ticker.C:
+				if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+					return
+				}
+				flusher.Flush()
+			case m, ok := <-sub:
+				if !ok {
+					return
+				}
+				if !match(m) {
+					continue
+				}
+				if err := writeMessage(w, b.Epoch(), m); err != nil {
+					log.Error(err, "Could not write message")
+					return
+				}
+				flusher.Flush()
+			}
+		}
+	})
+}
+
+// streamFilter returns a function matching messages for the commit id prefix
+// and comma separated types. Empty values match all messages.
+func streamFilter(commitID string, types string) func(stream.Message) bool {
+	typeSet := map[string]bool{}
+	for _, t := range strings.Split(types, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			typeSet[t] = true
+		}
+	}
+
+	return func(m stream.Message) bool {
+		if commitID != "" && !strings.HasPrefix(m.CommitID, commitID) {
+			return false
+		}
+		if len(typeSet) > 0 && !typeSet[m.Type] {
+			return false
+		}
+		return true
+	}
+}
+
+// parseEventID parses an event id formatted as epoch-id. An id without an epoch
+// is returned with a zero epoch, as it can't be from the current broker.
+func parseEventID(s string) (int64, uint64, error) {
+	epoch := int64(0)
+	if i := strings.Index(s, "-"); i >= 0 {
+		e, err := strconv.ParseInt(s[:i], 10, 64)
+		if err != nil {
+			return 0, 0, err
+		}
+		epoch = e
+		s = s[i+1:]
+	}
+
+	id, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return epoch, id, nil
+}
+
+func writeMessage(w http.ResponseWriter, epoch int64, m stream.Message) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "id: %d-%d\nevent: %v\ndata: %s\n\n", epoch, m.ID, m.Type, b)
+	return err
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	logr "github.com/go-logr/logr/testing"
+	"github.com/onsi/gomega"
+
+	"github.com/xenitab/flux-status/pkg/notifier"
+	"github.com/xenitab/flux-status/pkg/stream"
+)
+
+func TestStreamHandler(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	b := stream.NewBroker(10)
+	b.PublishEvent(notifier.Event{Type: notifier.EventTypeSync, CommitID: "foo"})
+	b.PublishEvent(notifier.Event{Type: notifier.EventTypeSync, CommitID: "bar"})
+	b.PublishEvent(notifier.Event{Type: notifier.EventTypeWorkload, CommitID: "bar"})
+	srv := httptest.NewServer(streamHandler(logr.TestLogger{T: t}, b))
+	defer srv.Close()
+
+	req, err := http.NewRequest("GET", srv.URL+"?commit=ba&type=sync,poll", nil)
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	req.Header.Set("Last-Event-ID", fmt.Sprintf("%d-1", b.Epoch()))
+	resp, err := http.DefaultClient.Do(req)
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	defer resp.Body.Close()
+	g.Expect(resp.StatusCode).Should(gomega.Equal(http.StatusOK))
+	g.Expect(resp.Header.Get("Content-Type")).Should(gomega.Equal("text/event-stream"))
+
+	b.Publish(stream.Message{Type: stream.TypePoll, CommitID: "bar"})
+	b.Publish(stream.Message{Type: stream.TypePoll, CommitID: "foo"})
+	b.Close()
+
+	lines := []string{}
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if strings.HasPrefix(scanner.Text(), "id: ") {
+			lines = append(lines, scanner.Text())
+		}
+	}
+	g.Expect(lines).Should(gomega.Equal([]string{
+		fmt.Sprintf("id: %d-2", b.Epoch()),
+		fmt.Sprintf("id: %d-4", b.Epoch()),
+	}))
+}
+
+func TestStreamHandlerOtherEpoch(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	b := stream.NewBroker(10)
+	b.PublishEvent(notifier.Event{Type: notifier.EventTypeSync, CommitID: "foo"})
+	b.PublishEvent(notifier.Event{Type: notifier.EventTypeSync, CommitID: "bar"})
+	srv := httptest.NewServer(streamHandler(logr.TestLogger{T: t}, b))
+	defer srv.Close()
+
+	// An id from before a restart replays the whole buffer
+	for _, lastEventID := range []string{fmt.Sprintf("%d-1", b.Epoch()-1), "1"} {
+		req, err := http.NewRequest("GET", srv.URL, nil)
+		g.Expect(err).ShouldNot(gomega.HaveOccurred())
+		req.Header.Set("Last-Event-ID", lastEventID)
+		ctx, cancel := context.WithCancel(context.Background())
+		resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+		g.Expect(err).ShouldNot(gomega.HaveOccurred())
+		g.Expect(resp.StatusCode).Should(gomega.Equal(http.StatusOK))
+
+		lines := []string{}
+		scanner := bufio.NewScanner(resp.Body)
+		for len(lines) < 2 && scanner.Scan() {
+			if strings.HasPrefix(scanner.Text(), "id: ") {
+				lines = append(lines, scanner.Text())
+			}
+		}
+		cancel()
+		resp.Body.Close()
+		g.Expect(lines).Should(gomega.Equal([]string{
+			fmt.Sprintf("id: %d-1", b.Epoch()),
+			fmt.Sprintf("id: %d-2", b.Epoch()),
+		}), lastEventID)
+	}
+}
+
+func TestStreamHandlerInvalidLastEventID(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	req := httptest.NewRequest("GET", "/api/v1/stream?lastEventId=foo", nil)
+	rr := httptest.NewRecorder()
+	streamHandler(logr.TestLogger{T: t}, stream.NewBroker(10)).ServeHTTP(rr, req)
+	g.Expect(rr.Code).Should(gomega.Equal(http.StatusBadRequest))
+}
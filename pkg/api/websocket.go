@@ -2,32 +2,34 @@ package api
 
 import (
 	"net/http"
+	"time"
 
+	"github.com/fluxcd/flux/pkg/http/websocket"
 	"github.com/go-logr/logr"
-	"github.com/gorilla/websocket"
+
+	"github.com/xenitab/flux-status/pkg/flux"
 )
 
-var upgrader = websocket.Upgrader{}
+// pingInterval is the duration between each RPC ping to the connected Flux daemon.
+const pingInterval = 30 * time.Second
 
-func websocketHandler(log logr.Logger) http.HandlerFunc {
+// websocketHandler acts as the upstream of Flux, accepting the websocket
+// connection and using it as an RPC client to the Flux API.
+func websocketHandler(log logr.Logger, upstream *flux.Upstream) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		log.Info("Received request", "url", r.URL)
-		c, err := upgrader.Upgrade(w, r, nil)
+		ws, err := websocket.Upgrade(w, r, nil)
 		if err != nil {
+			log.Error(err, "Could not upgrade websocket connection")
 			return
 		}
-		defer c.Close()
-
-		for {
-			mt, message, err := c.ReadMessage()
-			if err != nil {
-				break
-			}
 
-			err = c.WriteMessage(mt, message)
-			if err != nil {
-				break
-			}
+		log.Info("Flux connected")
+		err = upstream.Serve(r.Context(), ws, pingInterval)
+		if err != nil && !websocket.IsExpectedWSCloseError(err) {
+			log.Error(err, "Flux connection closed with error")
+			return
 		}
+		log.Info("Flux disconnected")
 	})
 }
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	v6 "github.com/fluxcd/flux/pkg/api/v6"
+	"github.com/fluxcd/flux/pkg/http/websocket"
+	"github.com/fluxcd/flux/pkg/remote"
+	"github.com/fluxcd/flux/pkg/remote/rpc"
+	"github.com/fluxcd/flux/pkg/resource"
+	logr "github.com/go-logr/logr/testing"
+	"github.com/onsi/gomega"
+
+	"github.com/xenitab/flux-status/pkg/flux"
+)
+
+func TestWebsocketUpstream(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	log := logr.TestLogger{T: t}
+	upstream := flux.NewUpstream()
+	srv := httptest.NewServer(websocketHandler(log, upstream))
+	defer srv.Close()
+
+	u, err := url.Parse(srv.URL)
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	u.Scheme = "ws"
+	ws, err := websocket.Dial(http.DefaultClient, "flux-status-test", "", u)
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+
+	services := []v6.ControllerStatus{
+		{
+			ID:     resource.MustParseID("namespace:deployment/resource-name"),
+			Status: "ready",
+		},
+	}
+	mock := &remote.MockServer{
+		VersionAnswer:      "1.20.0",
+		ListServicesAnswer: services,
+	}
+	rpcServer, err := rpc.NewServer(mock, 10*time.Second)
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	go rpcServer.ServeConn(ws)
+
+	g.Eventually(upstream.Connected).Should(gomega.BeTrue())
+	g.Expect(upstream.Version()).Should(gomega.Equal("1.20.0"))
+	res, err := upstream.ListServices(context.TODO(), "")
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(res).Should(gomega.HaveLen(1))
+
+	g.Expect(ws.Close()).Should(gomega.Succeed())
+	g.Eventually(upstream.Connected).Should(gomega.BeFalse())
+	_, err = upstream.ListServices(context.TODO(), "")
+	g.Expect(err).Should(gomega.MatchError(flux.ErrNotConnected))
+}
@@ -2,11 +2,28 @@ package flux
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"net/url"
 
 	"github.com/fluxcd/flux/pkg/api/v6"
+	transport "github.com/fluxcd/flux/pkg/http"
+	"github.com/fluxcd/flux/pkg/http/client"
 )
 
 // Client is an interface that wraps the basic Flux client methods.
 type Client interface {
 	ListServices(context.Context, string) ([]v6.ControllerStatus, error)
+	SyncStatus(context.Context, string) ([]string, error)
+	GitRepoConfig(context.Context, bool) (v6.GitConfig, error)
+}
+
+// NewClient creates a Client communicating with the Flux API at the given address.
+func NewClient(addr string) (Client, error) {
+	fluxURL, err := url.Parse(fmt.Sprintf("http://%v/api/flux", addr))
+	if err != nil {
+		return nil, err
+	}
+
+	return client.New(http.DefaultClient, transport.NewAPIRouter(), fluxURL.String(), ""), nil
 }
@@ -8,10 +8,37 @@ import (
 
 // Mock is a dummy implementation of the Client interface.
 type Mock struct {
-	Services []v6.ControllerStatus
+	Services  []v6.ControllerStatus
+	Unsynced  []string
+	GitConfig v6.GitConfig
+	// UnsyncedRefs overrides Unsynced for the refs it contains
+	UnsyncedRefs map[string][]string
 }
 
-// ListServices returns the contents of the Services variable.
+// ListServices returns the contents of the Services variable in the namespace, or all if empty.
 func (m *Mock) ListServices(ctx context.Context, namespace string) ([]v6.ControllerStatus, error) {
-	return m.Services, nil
+	if namespace == "" {
+		return m.Services, nil
+	}
+
+	result := []v6.ControllerStatus{}
+	for _, s := range m.Services {
+		if ns, _, _ := s.ID.Components(); ns == namespace {
+			result = append(result, s)
+		}
+	}
+	return result, nil
+}
+
+// SyncStatus returns the contents of the Unsynced variable, or of UnsyncedRefs for the ref if set.
+func (m *Mock) SyncStatus(ctx context.Context, ref string) ([]string, error) {
+	if unsynced, ok := m.UnsyncedRefs[ref]; ok {
+		return unsynced, nil
+	}
+	return m.Unsynced, nil
+}
+
+// GitRepoConfig returns the contents of the GitConfig variable.
+func (m *Mock) GitRepoConfig(ctx context.Context, regenerate bool) (v6.GitConfig, error) {
+	return m.GitConfig, nil
 }
@@ -0,0 +1,150 @@
+package flux
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/fluxcd/flux/pkg/api/v6"
+	"github.com/fluxcd/flux/pkg/remote/rpc"
+)
+
+// ErrNotConnected is returned when Flux has not connected to the upstream.
+var ErrNotConnected = errors.New("Flux is not connected")
+
+// Upstream is a Client communicating with Flux through the RPC connection that
+// Flux establishes with its upstream, configured with the --connect flag.
+type Upstream struct {
+	mu      sync.RWMutex
+	client  *rpc.RPCClientV11
+	version string
+}
+
+// NewUpstream creates and returns an Upstream instance.
+func NewUpstream() *Upstream {
+	return &Upstream{}
+}
+
+// Serve uses the connection to communicate with Flux until the connection is
+// closed or the context is done. The connection is pinged at the given interval
+// to detect when Flux stops responding.
+func (u *Upstream) Serve(ctx context.Context, rwc io.ReadWriteCloser, interval time.Duration) error {
+	conn := &watchedConn{ReadWriteCloser: rwc, done: make(chan struct{})}
+	defer conn.Close()
+	client := rpc.NewClientV11(conn)
+
+	if err := client.Ping(ctx); err != nil {
+		return err
+	}
+	version, err := client.Version(ctx)
+	if err != nil {
+		return err
+	}
+
+	u.set(client, version)
+	defer u.unset(client)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-conn.done:
+			return nil
+		case <-ticker.C:
+			if err := client.Ping(ctx); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Connected returns true if Flux is currently connected.
+func (u *Upstream) Connected() bool {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	return u.client != nil
+}
+
+// Version returns the version of the connected Flux daemon.
+func (u *Upstream) Version() string {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	return u.version
+}
+
+// ListServices returns the workloads in the namespace, or all namespaces if empty.
+func (u *Upstream) ListServices(ctx context.Context, namespace string) ([]v6.ControllerStatus, error) {
+	client, err := u.current()
+	if err != nil {
+		return nil, err
+	}
+
+	return client.ListServices(ctx, namespace)
+}
+
+// SyncStatus returns the commits between the ref and the last synced revision.
+func (u *Upstream) SyncStatus(ctx context.Context, ref string) ([]string, error) {
+	client, err := u.current()
+	if err != nil {
+		return nil, err
+	}
+
+	return client.SyncStatus(ctx, ref)
+}
+
+// GitRepoConfig returns the git configuration used by Flux.
+func (u *Upstream) GitRepoConfig(ctx context.Context, regenerate bool) (v6.GitConfig, error) {
+	client, err := u.current()
+	if err != nil {
+		return v6.GitConfig{}, err
+	}
+
+	return client.GitRepoConfig(ctx, regenerate)
+}
+
+func (u *Upstream) current() (*rpc.RPCClientV11, error) {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	if u.client == nil {
+		return nil, ErrNotConnected
+	}
+
+	return u.client, nil
+}
+
+func (u *Upstream) set(client *rpc.RPCClientV11, version string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.client = client
+	u.version = version
+}
+
+// unset removes the client, unless it has already been replaced by a newer connection.
+func (u *Upstream) unset(client *rpc.RPCClientV11) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.client != client {
+		return
+	}
+	u.client = nil
+	u.version = ""
+}
+
+// watchedConn closes the done channel when reading from the connection fails.
+type watchedConn struct {
+	io.ReadWriteCloser
+	once sync.Once
+	done chan struct{}
+}
+
+func (c *watchedConn) Read(p []byte) (int, error) {
+	n, err := c.ReadWriteCloser.Read(p)
+	if err != nil {
+		c.once.Do(func() { close(c.done) })
+	}
+	return n, err
+}
@@ -0,0 +1,366 @@
+package history
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/go-logr/logr"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/xenitab/flux-status/pkg/notifier"
+)
+
+// Kind represents the different kinds of entries recorded in the history.
+type Kind string
+
+// These constants represents all valid Kind values.
+const (
+	// KindEvent is an event received from Flux.
+	KindEvent Kind = "event"
+	// KindStatus is a status sent to the git provider.
+	KindStatus Kind = "status"
+	// KindPoll is the outcome of a workload poll.
+	KindPoll Kind = "poll"
+)
+
+// Entry is a single record in the history.
+type Entry struct {
+	Kind     Kind                     `json:"kind"`
+	Type     string                   `json:"type"`
+	CommitID string                   `json:"commitId"`
+	State    notifier.EventState      `json:"state,omitempty"`
+	Message  string                   `json:"message,omitempty"`
+	Errors   []notifier.ResourceError `json:"errors,omitempty"`
+	Pending  []string                 `json:"pending,omitempty"`
+	// Workloads has the detailed status of pending workloads when a poll failed
+	Workloads []notifier.WorkloadStatus `json:"workloads,omitempty"`
+	Outcome   string                    `json:"outcome,omitempty"`
+	// IncludedIn is the commit id of the sync whose status was copied to this commit
+	IncludedIn string    `json:"includedIn,omitempty"`
+	Time       time.Time `json:"time"`
+}
+
+// pollsBucket is the bucket storing the active poll of each instance.
+var pollsBucket = []byte("flux-status.polls")
+
+// Poll is the state of an active workload poll, stored to resume the poll after a restart.
+type Poll struct {
+	CommitIDs []string  `json:"commitIds"`
+	Snapshot  []string  `json:"snapshot"`
+	Changed   []string  `json:"changed,omitempty"`
+	Start     time.Time `json:"start"`
+	Deadline  time.Time `json:"deadline"`
+}
+
+// Store records the history of an instance in a bbolt database file. Entries are
+// keyed by time so that they can be iterated in the order they were added.
+type Store struct {
+	db        *bolt.DB
+	bucket    []byte
+	retention time.Duration
+	limit     int
+
+	// count is the number of entries, it is only modified within update transactions
+	count int
+}
+
+// Open opens or creates the database at the path and returns a Store for the instance.
+// Entries older than retention or exceeding limit are removed, zero disables either setting.
+func Open(path string, inst string, retention time.Duration, limit int) (*Store, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Store{
+		db:        db,
+		bucket:    []byte(inst),
+		retention: retention,
+		limit:     limit,
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(pollsBucket); err != nil {
+			return err
+		}
+		b, err := tx.CreateBucketIfNotExists(s.bucket)
+		if err != nil {
+			return err
+		}
+		s.count = b.Stats().KeyN
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Add records the entry and removes any entries outside of the retention.
+func (s *Store) Add(e Entry) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	value, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(s.bucket)
+		seq, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		if err := b.Put(entryKey(e.Time, seq), value); err != nil {
+			return err
+		}
+		s.count++
+
+		return s.prune(b)
+	})
+}
+
+// AddEvent records the notifier event as a status sent to the git provider.
+func (s *Store) AddEvent(e notifier.Event) error {
+	pending := []string{}
+	for _, id := range e.Pending {
+		pending = append(pending, id.String())
+	}
+
+	return s.Add(Entry{
+		Kind:       KindStatus,
+		Type:       string(e.Type),
+		CommitID:   e.CommitID,
+		State:      e.State,
+		Message:    e.Message,
+		Errors:     e.Errors,
+		Pending:    pending,
+		Workloads:  e.Workloads,
+		IncludedIn: e.IncludedIn,
+	})
+}
+
+// Deployments returns the last n sync statuses, starting with the most recent.
+func (s *Store) Deployments(n int) ([]Entry, error) {
+	return s.last(n, isDeployment)
+}
+
+// Workloads returns the last n final workload statuses, starting with the most recent.
+func (s *Store) Workloads(n int) ([]Entry, error) {
+	return s.last(n, isWorkloadStatus)
+}
+
+// last returns the last n entries matching the function, starting with the most recent.
+func (s *Store) last(n int, match func(Entry) bool) ([]Entry, error) {
+	entries := []Entry{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(s.bucket).Cursor()
+		for k, v := c.Last(); k != nil && len(entries) < n; k, v = c.Prev() {
+			e := Entry{}
+			if err := json.Unmarshal(v, &e); err != nil {
+				return err
+			}
+			if match(e) {
+				entries = append(entries, e)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// DeploymentsBetween returns the sync statuses recorded between from and to, starting with the oldest.
+func (s *Store) DeploymentsBetween(from time.Time, to time.Time) ([]Entry, error) {
+	entries := []Entry{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(s.bucket).Cursor()
+		max := entryKey(to, ^uint64(0))
+		for k, v := c.Seek(entryKey(from, 0)); k != nil && bytes.Compare(k, max) <= 0; k, v = c.Next() {
+			e := Entry{}
+			if err := json.Unmarshal(v, &e); err != nil {
+				return err
+			}
+			if isDeployment(e) {
+				entries = append(entries, e)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// Commit returns all entries recorded for the commit id, starting with the oldest.
+func (s *Store) Commit(commitID string) ([]Entry, error) {
+	if commitID == "" {
+		return nil, errors.New("Commit id can't be empty")
+	}
+
+	entries := []Entry{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(s.bucket).ForEach(func(k, v []byte) error {
+			e := Entry{}
+			if err := json.Unmarshal(v, &e); err != nil {
+				return err
+			}
+			if e.CommitID == commitID {
+				entries = append(entries, e)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// SavePoll stores the active poll of the instance, replacing any previous poll.
+func (s *Store) SavePoll(p Poll) error {
+	value, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(pollsBucket).Put(s.bucket, value)
+	})
+}
+
+// ActivePoll returns the active poll of the instance, or nil if there is none.
+func (s *Store) ActivePoll() (*Poll, error) {
+	var p *Poll
+	err := s.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(pollsBucket).Get(s.bucket)
+		if value == nil {
+			return nil
+		}
+		p = &Poll{}
+		return json.Unmarshal(value, p)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// ClearPoll removes the active poll if it was started for the commit id. Polls
+// for other commits are kept as they have replaced the poll being cleared.
+func (s *Store) ClearPoll(commitID string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(pollsBucket)
+		value := b.Get(s.bucket)
+		if value == nil {
+			return nil
+		}
+		p := Poll{}
+		if err := json.Unmarshal(value, &p); err != nil {
+			return err
+		}
+		if len(p.CommitIDs) == 0 || p.CommitIDs[0] != commitID {
+			return nil
+		}
+		return b.Delete(s.bucket)
+	})
+}
+
+// prune removes the oldest entries until both the retention and limit are met.
+func (s *Store) prune(b *bolt.Bucket) error {
+	// Keys are collected first as deleting while iterating makes the cursor skip keys
+	keys := [][]byte{}
+	c := b.Cursor()
+	for k, _ := c.First(); k != nil; k, _ = c.Next() {
+		excess := s.limit > 0 && s.count-len(keys) > s.limit
+		expired := s.retention > 0 && time.Since(keyTime(k)) > s.retention
+		if !excess && !expired {
+			break
+		}
+		keys = append(keys, k)
+	}
+
+	for _, k := range keys {
+		if err := b.Delete(k); err != nil {
+			return err
+		}
+		s.count--
+	}
+
+	return nil
+}
+
+// Notifier wraps a Notifier and records every final status successfully sent in the Store.
+type Notifier struct {
+	notifier.Notifier
+	Log   logr.Logger
+	Store *Store
+}
+
+// NewNotifier creates and returns a Notifier instance.
+func NewNotifier(l logr.Logger, n notifier.Notifier, s *Store) *Notifier {
+	return &Notifier{
+		Notifier: n,
+		Log:      l,
+		Store:    s,
+	}
+}
+
+// Send sends the event through the wrapped Notifier and records it, unless the status is pending.
+// The status has been sent when recording fails, so the error is only logged.
+func (n Notifier) Send(ctx context.Context, e notifier.Event) error {
+	if err := n.Notifier.Send(ctx, e); err != nil {
+		return err
+	}
+	if e.State == notifier.EventStatePending {
+		return nil
+	}
+	if err := n.Store.AddEvent(e); err != nil {
+		n.Log.Error(err, "Could not record status in history", "commit-id", e.CommitID, "type", e.Type)
+	}
+
+	return nil
+}
+
+// isDeployment returns true if the entry is the final sync status of a synced commit.
+// Statuses of commits included in the sync of another commit are not deployments.
+func isDeployment(e Entry) bool {
+	return e.Kind == KindStatus && e.Type == string(notifier.EventTypeSync) && e.State != notifier.EventStatePending && e.IncludedIn == ""
+}
+
+// isWorkloadStatus returns true if the entry is the final workload status of a commit.
+func isWorkloadStatus(e Entry) bool {
+	return e.Kind == KindStatus && e.Type == string(notifier.EventTypeWorkload) && e.State != notifier.EventStatePending
+}
+
+// entryKey returns a key sorted by time and then by sequence.
+func entryKey(t time.Time, seq uint64) []byte {
+	k := make([]byte, 16)
+	if t.After(time.Unix(0, 0)) {
+		binary.BigEndian.PutUint64(k[:8], uint64(t.UnixNano()))
+	}
+	binary.BigEndian.PutUint64(k[8:], seq)
+	return k
+}
+
+func keyTime(k []byte) time.Time {
+	return time.Unix(0, int64(binary.BigEndian.Uint64(k[:8])))
+}
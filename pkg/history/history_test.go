@@ -0,0 +1,202 @@
+package history
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	logr "github.com/go-logr/logr/testing"
+	"github.com/onsi/gomega"
+
+	"github.com/xenitab/flux-status/pkg/notifier"
+)
+
+func testStore(t *testing.T, retention time.Duration, limit int) (*Store, func()) {
+	dir, err := ioutil.TempDir("", "history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := Open(filepath.Join(dir, "history.db"), "dev", retention, limit)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s, func() {
+		s.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestDeployments(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	s, cleanup := testStore(t, 0, 0)
+	defer cleanup()
+
+	for _, id := range []string{"foo", "bar", "baz"} {
+		g.Expect(s.AddEvent(notifier.Event{Type: notifier.EventTypeSync, CommitID: id, State: notifier.EventStateSucceeded})).Should(gomega.Succeed())
+		g.Expect(s.AddEvent(notifier.Event{Type: notifier.EventTypeWorkload, CommitID: id, State: notifier.EventStateSucceeded})).Should(gomega.Succeed())
+	}
+	g.Expect(s.AddEvent(notifier.Event{Type: notifier.EventTypeSync, CommitID: "qux", State: notifier.EventStatePending})).Should(gomega.Succeed())
+
+	entries, err := s.Deployments(2)
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(entries).Should(gomega.HaveLen(2))
+	g.Expect(entries[0].CommitID).Should(gomega.Equal("baz"))
+	g.Expect(entries[1].CommitID).Should(gomega.Equal("bar"))
+}
+
+func TestDeploymentsIncluded(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	s, cleanup := testStore(t, 0, 0)
+	defer cleanup()
+
+	g.Expect(s.AddEvent(notifier.Event{Type: notifier.EventTypeSync, CommitID: "foo", State: notifier.EventStateSucceeded})).Should(gomega.Succeed())
+	g.Expect(s.AddEvent(notifier.Event{Type: notifier.EventTypeSync, CommitID: "bar", State: notifier.EventStateSucceeded, IncludedIn: "foo"})).Should(gomega.Succeed())
+
+	entries, err := s.Deployments(5)
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(entries).Should(gomega.HaveLen(1))
+	g.Expect(entries[0].CommitID).Should(gomega.Equal("foo"))
+
+	entries, err = s.Commit("bar")
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(entries).Should(gomega.HaveLen(1))
+	g.Expect(entries[0].IncludedIn).Should(gomega.Equal("foo"))
+}
+
+func TestWorkloads(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	s, cleanup := testStore(t, 0, 0)
+	defer cleanup()
+
+	for _, id := range []string{"foo", "bar"} {
+		g.Expect(s.AddEvent(notifier.Event{Type: notifier.EventTypeSync, CommitID: id, State: notifier.EventStateSucceeded})).Should(gomega.Succeed())
+		g.Expect(s.AddEvent(notifier.Event{Type: notifier.EventTypeWorkload, CommitID: id, State: notifier.EventStateFailed})).Should(gomega.Succeed())
+	}
+	g.Expect(s.AddEvent(notifier.Event{Type: notifier.EventTypeWorkload, CommitID: "baz", State: notifier.EventStatePending})).Should(gomega.Succeed())
+
+	entries, err := s.Workloads(5)
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(entries).Should(gomega.HaveLen(2))
+	g.Expect(entries[0].CommitID).Should(gomega.Equal("bar"))
+	g.Expect(entries[0].State).Should(gomega.Equal(notifier.EventStateFailed))
+	g.Expect(entries[1].CommitID).Should(gomega.Equal("foo"))
+}
+
+func TestDeploymentsBetween(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	s, cleanup := testStore(t, 0, 0)
+	defer cleanup()
+
+	now := time.Now()
+	for i, id := range []string{"foo", "bar", "baz"} {
+		e := Entry{Kind: KindStatus, Type: "sync", CommitID: id, Time: now.Add(time.Duration(i-3) * time.Hour)}
+		g.Expect(s.Add(e)).Should(gomega.Succeed())
+	}
+
+	entries, err := s.DeploymentsBetween(now.Add(-150*time.Minute), now.Add(-90*time.Minute))
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(entries).Should(gomega.HaveLen(1))
+	g.Expect(entries[0].CommitID).Should(gomega.Equal("bar"))
+
+	entries, err = s.DeploymentsBetween(time.Time{}, now)
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(entries).Should(gomega.HaveLen(3))
+	g.Expect(entries[0].CommitID).Should(gomega.Equal("foo"))
+}
+
+func TestCommit(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	s, cleanup := testStore(t, 0, 0)
+	defer cleanup()
+
+	g.Expect(s.Add(Entry{Kind: KindEvent, Type: "sync", CommitID: "foo"})).Should(gomega.Succeed())
+	g.Expect(s.Add(Entry{Kind: KindStatus, Type: "sync", CommitID: "bar"})).Should(gomega.Succeed())
+	g.Expect(s.Add(Entry{Kind: KindPoll, Type: "workload", CommitID: "foo", Outcome: "success"})).Should(gomega.Succeed())
+
+	entries, err := s.Commit("foo")
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(entries).Should(gomega.HaveLen(2))
+	g.Expect(entries[0].Kind).Should(gomega.Equal(KindEvent))
+	g.Expect(entries[1].Outcome).Should(gomega.Equal("success"))
+}
+
+func TestRetention(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	s, cleanup := testStore(t, time.Hour, 2)
+	defer cleanup()
+
+	g.Expect(s.Add(Entry{CommitID: "old", Time: time.Now().Add(-2 * time.Hour)})).Should(gomega.Succeed())
+	g.Expect(s.Add(Entry{CommitID: "foo"})).Should(gomega.Succeed())
+	g.Expect(s.Add(Entry{CommitID: "bar"})).Should(gomega.Succeed())
+	g.Expect(s.Add(Entry{CommitID: "baz"})).Should(gomega.Succeed())
+
+	for id, n := range map[string]int{"old": 0, "foo": 0, "bar": 1, "baz": 1} {
+		entries, err := s.Commit(id)
+		g.Expect(err).ShouldNot(gomega.HaveOccurred())
+		g.Expect(entries).Should(gomega.HaveLen(n), id)
+	}
+}
+
+func TestNotifier(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	s, cleanup := testStore(t, 0, 0)
+	defer cleanup()
+	mock := notifier.NewMock()
+	n := NewNotifier(logr.TestLogger{T: t}, mock, s)
+
+	e := notifier.Event{Type: notifier.EventTypeSync, CommitID: "foo", State: notifier.EventStateSucceeded}
+	g.Expect(n.Send(context.TODO(), e)).Should(gomega.Succeed())
+	g.Expect(mock.Events).Should(gomega.Receive(gomega.Equal(e)))
+	entries, err := s.Deployments(1)
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(entries).Should(gomega.HaveLen(1))
+	g.Expect(entries[0].State).Should(gomega.Equal(notifier.EventStateSucceeded))
+
+	// Pending statuses are sent but not recorded
+	pending := notifier.Event{Type: notifier.EventTypeWorkload, CommitID: "foo", State: notifier.EventStatePending}
+	g.Expect(n.Send(context.TODO(), pending)).Should(gomega.Succeed())
+	g.Expect(mock.Events).Should(gomega.Receive(gomega.Equal(pending)))
+	entries, err = s.Commit("foo")
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(entries).Should(gomega.HaveLen(1))
+}
+
+func TestNotifierRecordFailed(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	s, cleanup := testStore(t, 0, 0)
+	defer cleanup()
+	mock := notifier.NewMock()
+	n := NewNotifier(logr.TestLogger{T: t}, mock, s)
+	g.Expect(s.Close()).Should(gomega.Succeed())
+
+	e := notifier.Event{Type: notifier.EventTypeSync, CommitID: "foo", State: notifier.EventStateSucceeded}
+	g.Expect(n.Send(context.TODO(), e)).Should(gomega.Succeed())
+	g.Expect(mock.Events).Should(gomega.Receive(gomega.Equal(e)))
+}
+
+func TestPoll(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	s, cleanup := testStore(t, 0, 0)
+	defer cleanup()
+
+	p, err := s.ActivePoll()
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(p).Should(gomega.BeNil())
+
+	g.Expect(s.SavePoll(Poll{CommitIDs: []string{"foo"}})).Should(gomega.Succeed())
+	g.Expect(s.SavePoll(Poll{CommitIDs: []string{"bar", "baz"}, Snapshot: []string{"namespace:deployment/app"}})).Should(gomega.Succeed())
+	g.Expect(s.ClearPoll("foo")).Should(gomega.Succeed())
+	p, err = s.ActivePoll()
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(p.CommitIDs).Should(gomega.Equal([]string{"bar", "baz"}))
+	g.Expect(p.Snapshot).Should(gomega.Equal([]string{"namespace:deployment/app"}))
+
+	g.Expect(s.ClearPoll("bar")).Should(gomega.Succeed())
+	p, err = s.ActivePoll()
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(p).Should(gomega.BeNil())
+}
@@ -0,0 +1,318 @@
+package informer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fluxcd/flux/pkg/api/v6"
+	"github.com/fluxcd/flux/pkg/cluster"
+	"github.com/fluxcd/flux/pkg/resource"
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/xenitab/flux-status/pkg/poller"
+)
+
+// Statuses of the workloads, the same as used by Flux.
+const (
+	StatusReady    = "ready"
+	StatusUpdating = "updating"
+	StatusFailed   = "failed"
+)
+
+// IgnoreAnnotation removes a workload from health evaluation when set to "true".
+const IgnoreAnnotation = "flux-status.xenit.io/ignore"
+
+// DefaultHealthRules returns the rules used with the HealthChecker when no rules are configured.
+// Workloads are healthy when ready, and a failed workload fails the poll at once as it does not
+// recover without a new rollout.
+func DefaultHealthRules() poller.HealthRules {
+	rules := poller.DefaultHealthRules()
+	rules[poller.DefaultKind] = poller.HealthRule{
+		Healthy: []string{StatusReady},
+		Failed:  []string{StatusFailed},
+	}
+
+	return rules
+}
+
+// syncChecksumAnnotations are set by Flux on every resource it applies.
+var syncChecksumAnnotations = []string{"fluxcd.io/sync-checksum", "flux.weave.works/sync-checksum"}
+
+// fluxIgnoreAnnotations make Flux ignore a resource when set to "true".
+var fluxIgnoreAnnotations = []string{"fluxcd.io/ignore", "flux.weave.works/ignore"}
+
+// HealthChecker evaluates the health of Deployments, StatefulSets, DaemonSets and Jobs
+// from informer caches, using the same rules as kubectl rollout status.
+type HealthChecker struct {
+	Log logr.Logger
+
+	factory informers.SharedInformerFactory
+	changes chan struct{}
+}
+
+// NewHealthChecker creates and returns a HealthChecker instance. Resync is the
+// interval at which the informers notify about all workloads, zero disables it.
+func NewHealthChecker(l logr.Logger, client kubernetes.Interface, resync time.Duration) *HealthChecker {
+	h := &HealthChecker{
+		Log:     l,
+		factory: informers.NewSharedInformerFactory(client, resync),
+		// A single pending change is enough as every workload is evaluated on a change
+		changes: make(chan struct{}, 1),
+	}
+
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { h.notify() },
+		UpdateFunc: func(oldObj, newObj interface{}) { h.notify() },
+		DeleteFunc: func(obj interface{}) { h.notify() },
+	}
+	h.factory.Apps().V1().Deployments().Informer().AddEventHandler(handler)
+	h.factory.Apps().V1().StatefulSets().Informer().AddEventHandler(handler)
+	h.factory.Apps().V1().DaemonSets().Informer().AddEventHandler(handler)
+	h.factory.Batch().V1().Jobs().Informer().AddEventHandler(handler)
+
+	return h
+}
+
+// Start starts the informers and waits until their caches have synced.
+func (h *HealthChecker) Start(stop <-chan struct{}) error {
+	h.factory.Start(stop)
+	for informer, synced := range h.factory.WaitForCacheSync(stop) {
+		if !synced {
+			return fmt.Errorf("Could not sync informer cache for %v", informer)
+		}
+	}
+	h.Log.Info("Synced informer caches")
+
+	return nil
+}
+
+// Changes returns a channel that receives a value when any workload has changed.
+func (h *HealthChecker) Changes() <-chan struct{} {
+	return h.changes
+}
+
+// ListServices returns the workloads in the namespace, or all namespaces if empty, with their health.
+func (h *HealthChecker) ListServices(ctx context.Context, namespace string) ([]v6.ControllerStatus, error) {
+	result := []v6.ControllerStatus{}
+
+	deployments, err := h.factory.Apps().V1().Deployments().Lister().Deployments(namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range deployments {
+		result = append(result, workload(d.ObjectMeta, "deployment", deploymentStatus(d)))
+	}
+
+	statefulSets, err := h.factory.Apps().V1().StatefulSets().Lister().StatefulSets(namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range statefulSets {
+		result = append(result, workload(s.ObjectMeta, "statefulset", statefulSetStatus(s)))
+	}
+
+	daemonSets, err := h.factory.Apps().V1().DaemonSets().Lister().DaemonSets(namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range daemonSets {
+		result = append(result, workload(d.ObjectMeta, "daemonset", daemonSetStatus(d)))
+	}
+
+	jobs, err := h.factory.Batch().V1().Jobs().Lister().Jobs(namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	for _, j := range jobs {
+		result = append(result, workload(j.ObjectMeta, "job", jobStatus(j)))
+	}
+
+	return result, nil
+}
+
+// notify signals a change without blocking, changes are coalesced until received.
+func (h *HealthChecker) notify() {
+	select {
+	case h.changes <- struct{}{}:
+	default:
+	}
+}
+
+// status is the evaluated health of a workload.
+type status struct {
+	status  string
+	rollout cluster.RolloutStatus
+}
+
+// workload returns the workload status in the same form as Flux reports it. Workloads
+// that were not applied by Flux are marked as not in the repository, and workloads
+// ignored by Flux are marked as ignored.
+func workload(meta metav1.ObjectMeta, kind string, s status) v6.ControllerStatus {
+	w := v6.ControllerStatus{
+		ID:       resource.MakeID(meta.Namespace, kind, meta.Name),
+		Status:   s.status,
+		Rollout:  s.rollout,
+		ReadOnly: v6.ReadOnlyMissing,
+		Labels:   meta.Labels,
+		Policies: map[string]string{},
+	}
+	for _, a := range syncChecksumAnnotations {
+		if _, ok := meta.Annotations[a]; ok {
+			w.ReadOnly = v6.ReadOnlyOK
+		}
+	}
+	for _, a := range fluxIgnoreAnnotations {
+		if meta.Annotations[a] == "true" {
+			w.Ignore = true
+		}
+	}
+	if strings.EqualFold(meta.Annotations[IgnoreAnnotation], "true") {
+		w.Policies[poller.IgnorePolicy] = "true"
+	}
+
+	return w
+}
+
+// deploymentStatus evaluates the rollout of a Deployment like kubectl rollout status.
+func deploymentStatus(d *appsv1.Deployment) status {
+	desired := int32(1)
+	if d.Spec.Replicas != nil {
+		desired = *d.Spec.Replicas
+	}
+	s := status{
+		status: StatusUpdating,
+		rollout: cluster.RolloutStatus{
+			Desired:   desired,
+			Updated:   d.Status.UpdatedReplicas,
+			Ready:     d.Status.ReadyReplicas,
+			Available: d.Status.AvailableReplicas,
+			Outdated:  d.Status.Replicas - d.Status.UpdatedReplicas,
+		},
+	}
+
+	if d.Generation > d.Status.ObservedGeneration {
+		return s
+	}
+	for _, c := range d.Status.Conditions {
+		if c.Type == appsv1.DeploymentProgressing && c.Reason == "ProgressDeadlineExceeded" {
+			s.status = StatusFailed
+			s.rollout.Messages = []string{c.Message}
+			return s
+		}
+	}
+	if d.Status.UpdatedReplicas < desired || d.Status.Replicas > d.Status.UpdatedReplicas || d.Status.AvailableReplicas < d.Status.UpdatedReplicas {
+		return s
+	}
+
+	s.status = StatusReady
+	return s
+}
+
+// statefulSetStatus evaluates the rollout of a StatefulSet like kubectl rollout status.
+func statefulSetStatus(ss *appsv1.StatefulSet) status {
+	desired := int32(1)
+	if ss.Spec.Replicas != nil {
+		desired = *ss.Spec.Replicas
+	}
+	s := status{
+		status: StatusUpdating,
+		rollout: cluster.RolloutStatus{
+			Desired:   desired,
+			Updated:   ss.Status.UpdatedReplicas,
+			Ready:     ss.Status.ReadyReplicas,
+			Available: ss.Status.ReadyReplicas,
+			Outdated:  ss.Status.Replicas - ss.Status.UpdatedReplicas,
+		},
+	}
+
+	if ss.Status.ObservedGeneration == 0 || ss.Generation > ss.Status.ObservedGeneration {
+		return s
+	}
+	if ss.Status.ReadyReplicas < desired {
+		return s
+	}
+	// Only rolling updates have a rollout that can be waited on
+	if ss.Spec.UpdateStrategy.Type == appsv1.RollingUpdateStatefulSetStrategyType {
+		if ru := ss.Spec.UpdateStrategy.RollingUpdate; ru != nil && ru.Partition != nil && *ru.Partition > 0 {
+			if ss.Status.UpdatedReplicas < desired-*ru.Partition {
+				return s
+			}
+		} else if ss.Status.UpdateRevision != ss.Status.CurrentRevision {
+			return s
+		}
+	}
+
+	s.status = StatusReady
+	return s
+}
+
+// daemonSetStatus evaluates the rollout of a DaemonSet like kubectl rollout status.
+func daemonSetStatus(d *appsv1.DaemonSet) status {
+	outdated := d.Status.CurrentNumberScheduled - d.Status.UpdatedNumberScheduled
+	if outdated < 0 {
+		outdated = 0
+	}
+	s := status{
+		status: StatusUpdating,
+		rollout: cluster.RolloutStatus{
+			Desired:   d.Status.DesiredNumberScheduled,
+			Updated:   d.Status.UpdatedNumberScheduled,
+			Ready:     d.Status.NumberReady,
+			Available: d.Status.NumberAvailable,
+			Outdated:  outdated,
+		},
+	}
+
+	if d.Generation > d.Status.ObservedGeneration {
+		return s
+	}
+	// Only rolling updates have a rollout that can be waited on
+	if d.Spec.UpdateStrategy.Type == appsv1.RollingUpdateDaemonSetStrategyType {
+		if d.Status.UpdatedNumberScheduled < d.Status.DesiredNumberScheduled || d.Status.NumberAvailable < d.Status.DesiredNumberScheduled {
+			return s
+		}
+	}
+
+	s.status = StatusReady
+	return s
+}
+
+// jobStatus evaluates a Job from its conditions, as kubectl rollout status does not support Jobs.
+func jobStatus(j *batchv1.Job) status {
+	desired := int32(1)
+	if j.Spec.Completions != nil {
+		desired = *j.Spec.Completions
+	}
+	s := status{
+		status: StatusUpdating,
+		rollout: cluster.RolloutStatus{
+			Desired: desired,
+			Ready:   j.Status.Succeeded,
+		},
+	}
+
+	for _, c := range j.Status.Conditions {
+		if c.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch c.Type {
+		case batchv1.JobComplete:
+			s.status = StatusReady
+		case batchv1.JobFailed:
+			s.status = StatusFailed
+			s.rollout.Messages = []string{c.Message}
+		}
+	}
+
+	return s
+}
@@ -0,0 +1,216 @@
+package informer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v6 "github.com/fluxcd/flux/pkg/api/v6"
+	"github.com/fluxcd/flux/pkg/resource"
+	logr "github.com/go-logr/logr/testing"
+	"github.com/onsi/gomega"
+	"github.com/onsi/gomega/gstruct"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/xenitab/flux-status/pkg/flux"
+	"github.com/xenitab/flux-status/pkg/notifier"
+	"github.com/xenitab/flux-status/pkg/poller"
+)
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
+
+func testMeta(namespace string, name string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace:   namespace,
+		Name:        name,
+		Generation:  2,
+		Annotations: map[string]string{"fluxcd.io/sync-checksum": "abc"},
+	}
+}
+
+func TestHealthChecker(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	deployment := &appsv1.Deployment{
+		ObjectMeta: testMeta("default", "app"),
+		Spec:       appsv1.DeploymentSpec{Replicas: int32Ptr(2)},
+		Status: appsv1.DeploymentStatus{
+			ObservedGeneration: 2,
+			Replicas:           3,
+			UpdatedReplicas:    2,
+			ReadyReplicas:      3,
+			AvailableReplicas:  3,
+		},
+	}
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "migrate"},
+		Status: batchv1.JobStatus{
+			Conditions: []batchv1.JobCondition{
+				{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Message: "BackoffLimitExceeded"},
+			},
+		},
+	}
+	client := fake.NewSimpleClientset(deployment, job)
+	h := NewHealthChecker(logr.TestLogger{T: t}, client, 0)
+	stop := make(chan struct{})
+	defer close(stop)
+	g.Expect(h.Start(stop)).Should(gomega.Succeed())
+
+	ww, err := h.ListServices(context.TODO(), "")
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(ww).Should(gomega.ConsistOf(
+		gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
+			"ID":       gomega.Equal(resource.MustParseID("default:deployment/app")),
+			"Status":   gomega.Equal(StatusUpdating),
+			"ReadOnly": gomega.Equal(v6.ReadOnlyOK),
+		}),
+		gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
+			"ID":       gomega.Equal(resource.MustParseID("default:job/migrate")),
+			"Status":   gomega.Equal(StatusFailed),
+			"ReadOnly": gomega.Equal(v6.ReadOnlyMissing),
+		}),
+	))
+
+	// Drain the changes from the initial sync
+	g.Eventually(h.Changes()).Should(gomega.Receive())
+
+	// The old replica is terminated
+	deployment.Status.Replicas = 2
+	_, err = client.AppsV1().Deployments("default").UpdateStatus(deployment)
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Eventually(h.Changes()).Should(gomega.Receive())
+	g.Eventually(func() ([]v6.ControllerStatus, error) { return h.ListServices(context.TODO(), "default") }).Should(gomega.ContainElement(gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
+		"ID":     gomega.Equal(resource.MustParseID("default:deployment/app")),
+		"Status": gomega.Equal(StatusReady),
+	})))
+
+	ww, err = h.ListServices(context.TODO(), "other")
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(ww).Should(gomega.BeEmpty())
+}
+
+func TestPollFailed(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	deployment := &appsv1.Deployment{
+		ObjectMeta: testMeta("default", "app"),
+		Status: appsv1.DeploymentStatus{
+			ObservedGeneration: 2,
+			Conditions: []appsv1.DeploymentCondition{
+				{Type: appsv1.DeploymentProgressing, Reason: "ProgressDeadlineExceeded", Message: "Progress deadline exceeded"},
+			},
+		},
+	}
+	client := fake.NewSimpleClientset(deployment)
+	h := NewHealthChecker(logr.TestLogger{T: t}, client, 0)
+	stop := make(chan struct{})
+	defer close(stop)
+	g.Expect(h.Start(stop)).Should(gomega.Succeed())
+
+	events := make(chan notifier.Event)
+	noti := notifier.NewMock()
+	p := poller.NewPoller(logr.TestLogger{T: t}, noti, events, &flux.Mock{}, 3600, 0)
+	p.Rules = DefaultHealthRules()
+	p.Checker = h
+	go p.Start()
+
+	events <- notifier.Event{Type: notifier.EventTypeSync, CommitID: "foobar"}
+	g.Eventually(noti.Events, 5).Should(gomega.Receive(gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
+		"Type":    gomega.Equal(notifier.EventTypeWorkload),
+		"State":   gomega.Equal(notifier.EventStateFailed),
+		"Message": gomega.Equal("1 workloads have failed"),
+	})))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	g.Expect(p.Stop(ctx)).Should(gomega.Succeed())
+}
+
+func TestIgnoreAnnotation(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	meta := testMeta("default", "app")
+	meta.Annotations[IgnoreAnnotation] = "true"
+	w := workload(meta, "deployment", status{status: StatusReady})
+	g.Expect(w.Policies).Should(gomega.HaveKeyWithValue(poller.IgnorePolicy, "true"))
+	g.Expect(w.Ignore).Should(gomega.BeFalse())
+
+	meta = testMeta("default", "app")
+	meta.Annotations["fluxcd.io/ignore"] = "true"
+	w = workload(meta, "deployment", status{status: StatusReady})
+	g.Expect(w.Ignore).Should(gomega.BeTrue())
+	g.Expect(w.Policies).ShouldNot(gomega.HaveKey(poller.IgnorePolicy))
+}
+
+func TestDeploymentStatus(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	d := &appsv1.Deployment{
+		ObjectMeta: testMeta("default", "app"),
+		Spec:       appsv1.DeploymentSpec{Replicas: int32Ptr(1)},
+		Status: appsv1.DeploymentStatus{
+			ObservedGeneration: 1,
+			Replicas:           1,
+			UpdatedReplicas:    1,
+			AvailableReplicas:  1,
+		},
+	}
+	// The new generation has not been observed yet
+	g.Expect(deploymentStatus(d).status).Should(gomega.Equal(StatusUpdating))
+
+	d.Status.ObservedGeneration = 2
+	g.Expect(deploymentStatus(d).status).Should(gomega.Equal(StatusReady))
+
+	d.Status.Conditions = []appsv1.DeploymentCondition{
+		{Type: appsv1.DeploymentProgressing, Reason: "ProgressDeadlineExceeded", Message: "progress deadline exceeded"},
+	}
+	s := deploymentStatus(d)
+	g.Expect(s.status).Should(gomega.Equal(StatusFailed))
+	g.Expect(s.rollout.Messages).Should(gomega.ConsistOf("progress deadline exceeded"))
+}
+
+func TestStatefulSetStatus(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	ss := &appsv1.StatefulSet{
+		ObjectMeta: testMeta("default", "db"),
+		Spec: appsv1.StatefulSetSpec{
+			Replicas:       int32Ptr(3),
+			UpdateStrategy: appsv1.StatefulSetUpdateStrategy{Type: appsv1.RollingUpdateStatefulSetStrategyType},
+		},
+		Status: appsv1.StatefulSetStatus{
+			ObservedGeneration: 2,
+			ReadyReplicas:      3,
+			UpdatedReplicas:    1,
+			CurrentRevision:    "db-1",
+			UpdateRevision:     "db-2",
+		},
+	}
+	g.Expect(statefulSetStatus(ss).status).Should(gomega.Equal(StatusUpdating))
+
+	ss.Spec.UpdateStrategy.RollingUpdate = &appsv1.RollingUpdateStatefulSetStrategy{Partition: int32Ptr(2)}
+	g.Expect(statefulSetStatus(ss).status).Should(gomega.Equal(StatusReady))
+}
+
+func TestDaemonSetStatus(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	d := &appsv1.DaemonSet{
+		ObjectMeta: testMeta("default", "agent"),
+		Spec: appsv1.DaemonSetSpec{
+			UpdateStrategy: appsv1.DaemonSetUpdateStrategy{Type: appsv1.RollingUpdateDaemonSetStrategyType},
+		},
+		Status: appsv1.DaemonSetStatus{
+			ObservedGeneration:     2,
+			DesiredNumberScheduled: 3,
+			UpdatedNumberScheduled: 3,
+			NumberAvailable:        2,
+		},
+	}
+	g.Expect(daemonSetStatus(d).status).Should(gomega.Equal(StatusUpdating))
+
+	d.Status.NumberAvailable = 3
+	g.Expect(daemonSetStatus(d).status).Should(gomega.Equal(StatusReady))
+}
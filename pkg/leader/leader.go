@@ -0,0 +1,171 @@
+package leader
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// Default durations of the leader election, the same as used by the Kubernetes core components.
+const (
+	DefaultLeaseDuration = 15 * time.Second
+	DefaultRenewDeadline = 10 * time.Second
+	DefaultRetryPeriod   = 2 * time.Second
+)
+
+// Elector elects a single leader among the replicas using a Kubernetes Lease.
+// The identity of each replica is the address other replicas can reach it on.
+type Elector struct {
+	Log      logr.Logger
+	Identity string
+
+	elector *leaderelection.LeaderElector
+	lead    func(context.Context)
+	started chan context.Context
+	// The state observed by the elector is not safe to read while it runs,
+	// so the leadership is tracked from the callbacks instead
+	mu      sync.Mutex
+	leading bool
+	leader  string
+}
+
+// NewElector creates and returns an Elector instance competing for the Lease with the
+// name in the namespace. Lead is called when this replica becomes the leader, with a
+// context that is canceled when the leadership is lost. The election is not rejoined
+// before lead has returned.
+func NewElector(l logr.Logger, client kubernetes.Interface, namespace string, name string, identity string, lead func(context.Context)) (*Elector, error) {
+	e := &Elector{
+		Log:      l,
+		Identity: identity,
+		lead:     lead,
+		// The elector starts leading in a new goroutine, lead is called from Run instead
+		started: make(chan context.Context, 1),
+	}
+
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+		},
+		Client: client.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{
+			Identity: identity,
+		},
+	}
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		LeaseDuration:   DefaultLeaseDuration,
+		RenewDeadline:   DefaultRenewDeadline,
+		RetryPeriod:     DefaultRetryPeriod,
+		ReleaseOnCancel: true,
+		Name:            name,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				l.Info("Started leading", "identity", identity)
+				e.mu.Lock()
+				e.leader = identity
+				e.mu.Unlock()
+				e.started <- ctx
+			},
+			// Called every time the election stops, even if this replica never was the leader
+			OnStoppedLeading: func() {
+				e.mu.Lock()
+				defer e.mu.Unlock()
+				e.leading = false
+				if e.leader == identity {
+					e.leader = ""
+				}
+			},
+			OnNewLeader: func(leader string) {
+				l.Info("Observed new leader", "leader", leader)
+				e.mu.Lock()
+				defer e.mu.Unlock()
+				e.leader = leader
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	e.elector = elector
+
+	return e, nil
+}
+
+// Run competes for the leadership until the context is canceled. The Lease is
+// released when the context is canceled so that another replica can take over.
+func (e *Elector) Run(ctx context.Context) {
+	for {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			e.elector.Run(ctx)
+		}()
+		e.waitLead(done)
+		<-done
+
+		select {
+		case <-ctx.Done():
+			return
+		default:
+			e.Log.Info("Lost leadership, rejoining election")
+		}
+	}
+}
+
+// waitLead calls lead if this replica becomes the leader before the election is done,
+// and returns when lead has returned.
+func (e *Elector) waitLead(done <-chan struct{}) {
+	for {
+		select {
+		case ctx := <-e.started:
+			// The leadership of a previous election may not have been received
+			if ctx.Err() != nil {
+				continue
+			}
+			// This replica only reports being the leader while lead runs with a valid context,
+			// so that events are forwarded as soon as the components are being stopped
+			e.setLeading(true)
+			stopped := make(chan struct{})
+			go func() {
+				select {
+				case <-ctx.Done():
+					e.setLeading(false)
+				case <-stopped:
+				}
+			}()
+			e.lead(ctx)
+			close(stopped)
+			e.setLeading(false)
+			return
+		case <-done:
+			return
+		}
+	}
+}
+
+// IsLeader returns true if this replica is the leader.
+func (e *Elector) IsLeader() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.leading
+}
+
+// Leader returns the identity of the last observed leader, or an empty string if no leader has been observed.
+func (e *Elector) Leader() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.leader
+}
+
+func (e *Elector) setLeading(leading bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.leading = leading
+}
@@ -0,0 +1,89 @@
+package leader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	logr "github.com/go-logr/logr/testing"
+	"github.com/onsi/gomega"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestElector(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	client := fake.NewSimpleClientset()
+
+	firstLead := make(chan context.Context, 1)
+	first, err := NewElector(logr.TestLogger{T: t}, client, "flux", "flux-status", "10.0.0.1:3000", func(ctx context.Context) {
+		firstLead <- ctx
+		<-ctx.Done()
+	})
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	secondLead := make(chan context.Context, 1)
+	second, err := NewElector(logr.TestLogger{T: t}, client, "flux", "flux-status", "10.0.0.2:3000", func(ctx context.Context) {
+		secondLead <- ctx
+		<-ctx.Done()
+	})
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+
+	firstCtx, firstCancel := context.WithCancel(context.Background())
+	firstDone := make(chan struct{})
+	go func() {
+		defer close(firstDone)
+		first.Run(firstCtx)
+	}()
+	var leadCtx context.Context
+	g.Eventually(firstLead, 5*time.Second).Should(gomega.Receive(&leadCtx))
+	g.Expect(first.IsLeader()).Should(gomega.BeTrue())
+
+	secondCtx, secondCancel := context.WithCancel(context.Background())
+	defer secondCancel()
+	secondDone := make(chan struct{})
+	go func() {
+		defer close(secondDone)
+		second.Run(secondCtx)
+	}()
+	g.Eventually(second.Leader, 5*time.Second).Should(gomega.Equal("10.0.0.1:3000"))
+	g.Expect(second.IsLeader()).Should(gomega.BeFalse())
+
+	// The lease is released when the leader stops so the follower can take over
+	firstCancel()
+	<-firstDone
+	g.Eventually(leadCtx.Done()).Should(gomega.BeClosed())
+	g.Eventually(secondLead, 10*time.Second).Should(gomega.Receive())
+	g.Expect(second.IsLeader()).Should(gomega.BeTrue())
+	g.Expect(second.Leader()).Should(gomega.Equal("10.0.0.2:3000"))
+
+	secondCancel()
+	<-secondDone
+}
+
+func TestElectorWaitsForLead(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	client := fake.NewSimpleClientset()
+
+	leading := make(chan struct{})
+	release := make(chan struct{})
+	e, err := NewElector(logr.TestLogger{T: t}, client, "flux", "flux-status", "10.0.0.1:3000", func(ctx context.Context) {
+		close(leading)
+		<-ctx.Done()
+		<-release
+	})
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		e.Run(ctx)
+	}()
+	g.Eventually(leading, 5*time.Second).Should(gomega.BeClosed())
+
+	// Run does not return while lead is still running
+	cancel()
+	g.Consistently(done, 500*time.Millisecond).ShouldNot(gomega.BeClosed())
+	close(release)
+	g.Eventually(done, 5*time.Second).Should(gomega.BeClosed())
+	g.Expect(e.IsLeader()).Should(gomega.BeFalse())
+}
@@ -0,0 +1,111 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/xenitab/flux-status/pkg/notifier"
+)
+
+const namespace = "flux_status"
+
+// Outcomes of a workload poll.
+const (
+	PollSucceeded = "success"
+	PollTimeout   = "timeout"
+	PollCanceled  = "canceled"
+	PollError     = "error"
+	PollFailed    = "failed"
+)
+
+// States of received events that did not result in a commit status, other events have the
+// state of the status of their head commit.
+const (
+	EventUnknown  = "unknown"
+	EventRejected = "rejected"
+	EventIgnored  = "ignored"
+)
+
+var (
+	// EventsReceived counts the events received from Flux by type and state, once per event.
+	EventsReceived = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "events_received_total",
+		Help:      "Number of events received from Flux by type and state.",
+	}, []string{"type", "state"})
+
+	// NotifierSends counts the attempts to send a status to the git provider.
+	NotifierSends = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "notifier_sends_total",
+		Help:      "Number of attempts to send a status to the git provider.",
+	}, []string{"provider"})
+
+	// NotifierFailures counts the statuses that could not be sent to the git provider.
+	NotifierFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "notifier_failures_total",
+		Help:      "Number of statuses that could not be sent to the git provider.",
+	}, []string{"provider"})
+
+	// NotifierDuration observes the time it takes to send a status to the git provider.
+	NotifierDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "notifier_send_duration_seconds",
+		Help:      "Time it takes to send a status to the git provider.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"provider"})
+
+	// PollDuration observes the duration of workload polls by outcome.
+	PollDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "poll_duration_seconds",
+		Help:      "Duration of workload polls by outcome.",
+		Buckets:   []float64{5, 10, 30, 60, 120, 300, 600, 1200},
+	}, []string{"outcome"})
+
+	// PendingWorkloads is the number of workloads that are not yet healthy in the running poll.
+	PendingWorkloads = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "pending_workloads",
+		Help:      "Number of workloads that are not yet healthy in the running poll.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(EventsReceived, NotifierSends, NotifierFailures, NotifierDuration, PollDuration, PendingWorkloads)
+}
+
+// ObservePoll records the outcome and duration of a workload poll.
+func ObservePoll(outcome string, start time.Time) {
+	PollDuration.WithLabelValues(outcome).Observe(time.Since(start).Seconds())
+}
+
+// Notifier wraps a Notifier and records metrics for every event sent.
+type Notifier struct {
+	notifier.Notifier
+	Provider string
+}
+
+// NewNotifier creates and returns a Notifier instance labeling the metrics with the name of the wrapped Notifier.
+func NewNotifier(n notifier.Notifier) *Notifier {
+	return &Notifier{
+		Notifier: n,
+		Provider: n.String(),
+	}
+}
+
+// Send sends the event through the wrapped Notifier and records the result.
+func (n Notifier) Send(ctx context.Context, e notifier.Event) error {
+	start := time.Now()
+	err := n.Notifier.Send(ctx, e)
+	NotifierSends.WithLabelValues(n.Provider).Inc()
+	NotifierDuration.WithLabelValues(n.Provider).Observe(time.Since(start).Seconds())
+	if err != nil {
+		NotifierFailures.WithLabelValues(n.Provider).Inc()
+	}
+
+	return err
+}
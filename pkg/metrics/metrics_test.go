@@ -0,0 +1,42 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/xenitab/flux-status/pkg/notifier"
+)
+
+type failingNotifier struct {
+	notifier.Notifier
+}
+
+func (failingNotifier) Send(ctx context.Context, e notifier.Event) error {
+	return errors.New("failed")
+}
+
+func TestNotifier(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	mock := notifier.NewMock()
+	n := NewNotifier(mock)
+
+	// The counters are global so only the change is compared
+	sends := testutil.ToFloat64(NotifierSends.WithLabelValues("Mock"))
+	failures := testutil.ToFloat64(NotifierFailures.WithLabelValues("Mock"))
+	err := n.Send(context.TODO(), notifier.Event{})
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(testutil.ToFloat64(NotifierSends.WithLabelValues("Mock")) - sends).Should(gomega.Equal(1.0))
+	g.Expect(testutil.ToFloat64(NotifierFailures.WithLabelValues("Mock")) - failures).Should(gomega.Equal(0.0))
+
+	f := &Notifier{Notifier: failingNotifier{mock}, Provider: "Failing"}
+	sends = testutil.ToFloat64(NotifierSends.WithLabelValues("Failing"))
+	failures = testutil.ToFloat64(NotifierFailures.WithLabelValues("Failing"))
+	err = f.Send(context.TODO(), notifier.Event{})
+	g.Expect(err).Should(gomega.HaveOccurred())
+	g.Expect(testutil.ToFloat64(NotifierSends.WithLabelValues("Failing")) - sends).Should(gomega.Equal(1.0))
+	g.Expect(testutil.ToFloat64(NotifierFailures.WithLabelValues("Failing")) - failures).Should(gomega.Equal(1.0))
+}
@@ -2,7 +2,6 @@ package notifier
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"net/url"
 	"strings"
@@ -53,13 +52,15 @@ func (azdo AzureDevops) Send(ctx context.Context, e Event) error {
 	genre := StatusID
 	name := fmt.Sprintf("%v/%v", azdo.instance, e.Type)
 	state := toAzdoState(e.State)
+	// Azure DevOps does not limit the description length so the full report can be used
+	description := e.Report()
 
 	args := git.CreateCommitStatusArgs{
 		Project:      &azdo.projectID,
 		RepositoryId: &azdo.repositoryID,
 		CommitId:     &e.CommitID,
 		GitCommitStatusToCreate: &git.GitStatus{
-			Description: &e.Message,
+			Description: &description,
 			State:       &state,
 			Context: &git.GitStatusContext{
 				Genre: &genre,
@@ -75,6 +76,97 @@ func (azdo AzureDevops) Send(ctx context.Context, e Event) error {
 	return nil
 }
 
+// Comment creates or updates a comment describing the event on every pull request
+// containing the commit. Azure DevOps does not support comments on commits.
+func (azdo AzureDevops) Comment(ctx context.Context, e Event) error {
+	body := commentBody(azdo.instance, e)
+
+	queryType := git.GitPullRequestQueryTypeValues.Commit
+	args := git.GetPullRequestQueryArgs{
+		Project:      &azdo.projectID,
+		RepositoryId: &azdo.repositoryID,
+		Queries: &git.GitPullRequestQuery{
+			Queries: &[]git.GitPullRequestQueryInput{
+				{
+					Type:  &queryType,
+					Items: &[]string{e.CommitID},
+				},
+			},
+		},
+	}
+	query, err := azdo.client.GetPullRequestQuery(ctx, args)
+	if err != nil {
+		return err
+	}
+	if query.Results == nil {
+		return nil
+	}
+
+	for _, result := range *query.Results {
+		for _, pr := range result[e.CommitID] {
+			if pr.PullRequestId == nil {
+				continue
+			}
+
+			if err := azdo.commentPullRequest(ctx, e, *pr.PullRequestId, body); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (azdo AzureDevops) commentPullRequest(ctx context.Context, e Event, id int, body string) error {
+	threadsArgs := git.GetThreadsArgs{
+		Project:       &azdo.projectID,
+		RepositoryId:  &azdo.repositoryID,
+		PullRequestId: &id,
+	}
+	threads, err := azdo.client.GetThreads(ctx, threadsArgs)
+	if err != nil {
+		return err
+	}
+
+	for _, thread := range *threads {
+		if thread.Comments == nil || len(*thread.Comments) == 0 {
+			continue
+		}
+		comment := (*thread.Comments)[0]
+		if comment.Content == nil || !isComment(*comment.Content, azdo.instance, e.Type) || !strings.Contains(*comment.Content, e.CommitID) {
+			continue
+		}
+
+		updateArgs := git.UpdateCommentArgs{
+			Project:       &azdo.projectID,
+			RepositoryId:  &azdo.repositoryID,
+			PullRequestId: &id,
+			ThreadId:      thread.Id,
+			CommentId:     comment.Id,
+			Comment: &git.Comment{
+				Content: &body,
+			},
+		}
+		_, err := azdo.client.UpdateComment(ctx, updateArgs)
+		return err
+	}
+
+	createArgs := git.CreateThreadArgs{
+		Project:       &azdo.projectID,
+		RepositoryId:  &azdo.repositoryID,
+		PullRequestId: &id,
+		CommentThread: &git.GitPullRequestCommentThread{
+			Comments: &[]git.Comment{
+				{
+					Content: &body,
+				},
+			},
+		},
+	}
+	_, err = azdo.client.CreateThread(ctx, createArgs)
+	return err
+}
+
 // Get returns the status of a given commit id in a AzureDevops repository.
 func (azdo AzureDevops) Get(commitID string, action string) (*Status, error) {
 	ctx := context.Background()
@@ -100,7 +192,36 @@ func (azdo AzureDevops) Get(commitID string, action string) (*Status, error) {
 		}, nil
 	}
 
-	return nil, errors.New("No status found")
+	return nil, ErrStatusNotFound
+}
+
+// Revision returns the commit id that the given branch points to in a AzureDevops repository.
+func (azdo AzureDevops) Revision(ctx context.Context, ref string) (string, error) {
+	args := git.GetBranchArgs{
+		Project:      &azdo.projectID,
+		RepositoryId: &azdo.repositoryID,
+		Name:         &ref,
+	}
+	branch, err := azdo.client.GetBranch(ctx, args)
+	if err != nil {
+		return "", err
+	}
+
+	if branch.Commit == nil || branch.Commit.CommitId == nil {
+		return "", fmt.Errorf("Branch %v has no commit", ref)
+	}
+
+	return *branch.Commit.CommitId, nil
+}
+
+// Authenticate verifies that the repository can be accessed with the configured PAT.
+func (azdo AzureDevops) Authenticate(ctx context.Context) error {
+	args := git.GetRepositoryArgs{
+		Project:      &azdo.projectID,
+		RepositoryId: &azdo.repositoryID,
+	}
+	_, err := azdo.client.GetRepository(ctx, args)
+	return err
 }
 
 // String returns the name of the struct.
@@ -117,6 +238,9 @@ func toAzdoState(s EventState) git.GitStatusState {
 		return git.GitStatusStateValues.Pending
 	case EventStateSucceeded:
 		return git.GitStatusStateValues.Succeeded
+	case EventStateCanceled:
+		// Azure DevOps has no canceled state, not applicable is neither a success nor a failure
+		return git.GitStatusStateValues.NotApplicable
 	default:
 		return git.GitStatusStateValues.NotSet
 	}
@@ -135,7 +259,7 @@ func fromAzdoState(s git.GitStatusState) EventState {
 	case git.GitStatusStateValues.NotSet:
 		return EventStateFailed
 	case git.GitStatusStateValues.NotApplicable:
-		return EventStateFailed
+		return EventStateCanceled
 	default:
 		return EventStateFailed
 	}
@@ -25,3 +25,10 @@ func TestParseAzdoURLSsh(t *testing.T) {
 	g.Expect(c.projectID).Should(gomega.Equal("proj"))
 	g.Expect(c.repositoryID).Should(gomega.Equal("repo"))
 }
+
+func TestAzdoState(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	for _, s := range []EventState{EventStateFailed, EventStatePending, EventStateSucceeded, EventStateCanceled} {
+		g.Expect(fromAzdoState(toAzdoState(s))).Should(gomega.Equal(s))
+	}
+}
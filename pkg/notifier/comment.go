@@ -0,0 +1,58 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+)
+
+// CommentNotifier wraps a Notifier and comments on the commit, and any pull
+// request introducing it, when an event has failed.
+type CommentNotifier struct {
+	Notifier
+	Log logr.Logger
+}
+
+// NewCommentNotifier creates and returns a CommentNotifier instance.
+func NewCommentNotifier(l logr.Logger, n Notifier) *CommentNotifier {
+	return &CommentNotifier{
+		Notifier: n,
+		Log:      l,
+	}
+}
+
+// Send sets the commit status and comments on the commit if the event has failed.
+// The status has been set when commenting fails, so the error is only logged.
+func (c CommentNotifier) Send(ctx context.Context, e Event) error {
+	if err := c.Notifier.Send(ctx, e); err != nil {
+		return err
+	}
+
+	if e.State != EventStateFailed {
+		return nil
+	}
+
+	if err := c.Notifier.Comment(ctx, e); err != nil {
+		c.Log.Error(err, "Could not comment on commit", "commit-id", e.CommitID, "type", e.Type)
+	}
+
+	return nil
+}
+
+// commentMarker returns a hidden identifier used to find previous comments for the same event type.
+func commentMarker(inst string, t EventType) string {
+	return fmt.Sprintf("<!-- %v/%v/%v -->", StatusID, inst, t)
+}
+
+// isComment returns true if the comment body was created for the same instance and event type.
+func isComment(body string, inst string, t EventType) bool {
+	return strings.HasPrefix(body, commentMarker(inst, t))
+}
+
+// commentBody returns the markdown comment describing the event.
+func commentBody(inst string, e Event) string {
+	return fmt.Sprintf("%v\n**%v** `%v` %v %v for commit %v.\n\n```\n%v\n```\n",
+		commentMarker(inst, e.Type), StatusID, inst, e.Type, e.State, e.CommitID, e.Report())
+}
@@ -0,0 +1,75 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	logr "github.com/go-logr/logr/testing"
+	"github.com/onsi/gomega"
+)
+
+func TestCommentNotifierFailed(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	mock := NewMock()
+	noti := NewCommentNotifier(logr.TestLogger{T: t}, mock)
+
+	e := Event{
+		Type:     EventTypeSync,
+		CommitID: "foobar",
+		State:    EventStateFailed,
+	}
+	err := noti.Send(context.TODO(), e)
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(mock.Events).Should(gomega.Receive(gomega.Equal(e)))
+	g.Expect(mock.Comments).Should(gomega.Receive(gomega.Equal(e)))
+}
+
+func TestCommentNotifierSucceeded(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	mock := NewMock()
+	noti := NewCommentNotifier(logr.TestLogger{T: t}, mock)
+
+	e := Event{
+		Type:     EventTypeSync,
+		CommitID: "foobar",
+		State:    EventStateSucceeded,
+	}
+	err := noti.Send(context.TODO(), e)
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(mock.Events).Should(gomega.Receive(gomega.Equal(e)))
+	g.Expect(mock.Comments).ShouldNot(gomega.Receive())
+}
+
+func TestCommentNotifierCommentFailed(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	mock := NewMock()
+	mock.CommentErr = errors.New("forbidden")
+	noti := NewCommentNotifier(logr.TestLogger{T: t}, mock)
+
+	e := Event{
+		Type:     EventTypeSync,
+		CommitID: "foobar",
+		State:    EventStateFailed,
+	}
+	err := noti.Send(context.TODO(), e)
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(mock.Events).Should(gomega.Receive(gomega.Equal(e)))
+	g.Expect(mock.Comments).Should(gomega.Receive(gomega.Equal(e)))
+}
+
+func TestCommentBody(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	e := testErrorEvent()
+	e.Type = EventTypeSync
+	e.State = EventStateFailed
+	e.CommitID = "foobar"
+
+	body := commentBody("dev", e)
+	g.Expect(isComment(body, "dev", EventTypeSync)).Should(gomega.BeTrue())
+	g.Expect(isComment(body, "dev", EventTypeWorkload)).Should(gomega.BeFalse())
+	g.Expect(isComment(body, "prod", EventTypeSync)).Should(gomega.BeFalse())
+	g.Expect(strings.Contains(body, e.Report())).Should(gomega.BeTrue())
+	g.Expect(strings.Contains(body, "foobar")).Should(gomega.BeTrue())
+}
@@ -13,6 +13,9 @@ import (
 	"golang.org/x/oauth2"
 )
 
+// githubDescriptionLimit is the max length of a GitHub commit status description.
+const githubDescriptionLimit = 140
+
 // GitHub handles events for Github repositories.
 type GitHub struct {
 	Instance   string
@@ -53,9 +56,10 @@ func (g GitHub) Send(ctx context.Context, e Event) error {
 	}
 
 	githubContext := fmt.Sprintf("%v/%v/%v", StatusID, g.Instance, e.Type)
+	description := e.Summary(githubDescriptionLimit)
 	status := &github.RepoStatus{
 		State:       &state,
-		Description: &e.Message,
+		Description: &description,
 		Context:     &githubContext,
 	}
 
@@ -67,6 +71,69 @@ func (g GitHub) Send(ctx context.Context, e Event) error {
 	return nil
 }
 
+// Comment creates or updates a comment describing the event on the commit and
+// on every pull request containing the commit.
+func (g GitHub) Comment(ctx context.Context, e Event) error {
+	body := commentBody(g.Instance, e)
+
+	if err := g.commentCommit(ctx, e, body); err != nil {
+		return err
+	}
+
+	prs, _, err := g.Client.PullRequests.ListPullRequestsWithCommit(ctx, g.Owner, g.Repository, e.CommitID, nil)
+	if err != nil {
+		return err
+	}
+	for _, pr := range prs {
+		if err := g.commentPullRequest(ctx, e, pr.GetNumber(), body); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (g GitHub) commentCommit(ctx context.Context, e Event, body string) error {
+	comments, _, err := g.Client.Repositories.ListCommitComments(ctx, g.Owner, g.Repository, e.CommitID, &github.ListOptions{PerPage: 100})
+	if err != nil {
+		return err
+	}
+
+	comment := &github.RepositoryComment{Body: &body}
+	for _, c := range comments {
+		if !isComment(c.GetBody(), g.Instance, e.Type) {
+			continue
+		}
+
+		_, _, err := g.Client.Repositories.UpdateComment(ctx, g.Owner, g.Repository, c.GetID(), comment)
+		return err
+	}
+
+	_, _, err = g.Client.Repositories.CreateComment(ctx, g.Owner, g.Repository, e.CommitID, comment)
+	return err
+}
+
+func (g GitHub) commentPullRequest(ctx context.Context, e Event, number int, body string) error {
+	opts := &github.IssueListCommentsOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	comments, _, err := g.Client.Issues.ListComments(ctx, g.Owner, g.Repository, number, opts)
+	if err != nil {
+		return err
+	}
+
+	comment := &github.IssueComment{Body: &body}
+	for _, c := range comments {
+		if !(isComment(c.GetBody(), g.Instance, e.Type) && strings.Contains(c.GetBody(), e.CommitID)) {
+			continue
+		}
+
+		_, _, err := g.Client.Issues.EditComment(ctx, g.Owner, g.Repository, c.GetID(), comment)
+		return err
+	}
+
+	_, _, err = g.Client.Issues.CreateComment(ctx, g.Owner, g.Repository, number, comment)
+	return err
+}
+
 // Get returns the status of a given commit id in a Github repository.
 func (g GitHub) Get(commitID string, action string) (*Status, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
@@ -83,7 +150,7 @@ func (g GitHub) Get(commitID string, action string) (*Status, error) {
 			continue
 		}
 
-		if comp[1] != g.Instance && comp[2] != action {
+		if comp[1] != g.Instance || comp[2] != action {
 			continue
 		}
 
@@ -98,7 +165,23 @@ func (g GitHub) Get(commitID string, action string) (*Status, error) {
 		}, nil
 	}
 
-	return nil, errors.New("No status found")
+	return nil, ErrStatusNotFound
+}
+
+// Revision returns the commit id that the given ref points to in a Github repository.
+func (g GitHub) Revision(ctx context.Context, ref string) (string, error) {
+	sha, _, err := g.Client.Repositories.GetCommitSHA1(ctx, g.Owner, g.Repository, ref, "")
+	if err != nil {
+		return "", err
+	}
+
+	return sha, nil
+}
+
+// Authenticate verifies that the repository can be accessed with the configured token.
+func (g GitHub) Authenticate(ctx context.Context) error {
+	_, _, err := g.Client.Repositories.Get(ctx, g.Owner, g.Repository)
+	return err
 }
 
 // String returns the name of the struct.
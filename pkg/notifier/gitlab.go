@@ -10,6 +10,9 @@ import (
 	"github.com/xanzy/go-gitlab"
 )
 
+// gitlabDescriptionLimit is the max length of a Gitlab commit status description.
+const gitlabDescriptionLimit = 255
+
 // Gitlab handles events for Gitlab repositories.
 type Gitlab struct {
 	instance string
@@ -45,9 +48,10 @@ func NewGitlab(inst string, url string, token string) (*Gitlab, error) {
 // Send sets the status for a given commit id in a Gitlab repository.
 func (g Gitlab) Send(ctx context.Context, e Event) error {
 	name := fmt.Sprintf("%v/%v/%v", StatusID, g.instance, e.Type)
+	description := e.Summary(gitlabDescriptionLimit)
 	options := &gitlab.SetCommitStatusOptions{
 		State:       toGitlabState(e.State),
-		Description: &e.Message,
+		Description: &description,
 		Name:        &name,
 	}
 
@@ -59,6 +63,72 @@ func (g Gitlab) Send(ctx context.Context, e Event) error {
 	return nil
 }
 
+// Comment creates or updates a comment describing the event on the commit and
+// on every merge request containing the commit.
+func (g Gitlab) Comment(ctx context.Context, e Event) error {
+	body := commentBody(g.instance, e)
+
+	if err := g.commentCommit(ctx, e, body); err != nil {
+		return err
+	}
+
+	mrs, _, err := g.client.Commits.GetMergeRequestsByCommit(g.id, e.CommitID, gitlab.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	for _, mr := range mrs {
+		if err := g.commentMergeRequest(ctx, e, mr.IID, body); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (g Gitlab) commentCommit(ctx context.Context, e Event, body string) error {
+	opts := &gitlab.ListCommitDiscussionsOptions{PerPage: 100}
+	discussions, _, err := g.client.Discussions.ListCommitDiscussions(g.id, e.CommitID, opts, gitlab.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+
+	for _, d := range discussions {
+		if len(d.Notes) == 0 || !isComment(d.Notes[0].Body, g.instance, e.Type) {
+			continue
+		}
+
+		updateOpts := &gitlab.UpdateCommitDiscussionNoteOptions{Body: &body}
+		_, _, err := g.client.Discussions.UpdateCommitDiscussionNote(g.id, e.CommitID, d.ID, d.Notes[0].ID, updateOpts, gitlab.WithContext(ctx))
+		return err
+	}
+
+	createOpts := &gitlab.CreateCommitDiscussionOptions{Body: &body}
+	_, _, err = g.client.Discussions.CreateCommitDiscussion(g.id, e.CommitID, createOpts, gitlab.WithContext(ctx))
+	return err
+}
+
+func (g Gitlab) commentMergeRequest(ctx context.Context, e Event, iid int, body string) error {
+	opts := &gitlab.ListMergeRequestNotesOptions{ListOptions: gitlab.ListOptions{PerPage: 100}}
+	notes, _, err := g.client.Notes.ListMergeRequestNotes(g.id, iid, opts, gitlab.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+
+	for _, n := range notes {
+		if !(isComment(n.Body, g.instance, e.Type) && strings.Contains(n.Body, e.CommitID)) {
+			continue
+		}
+
+		updateOpts := &gitlab.UpdateMergeRequestNoteOptions{Body: &body}
+		_, _, err := g.client.Notes.UpdateMergeRequestNote(g.id, iid, n.ID, updateOpts, gitlab.WithContext(ctx))
+		return err
+	}
+
+	createOpts := &gitlab.CreateMergeRequestNoteOptions{Body: &body}
+	_, _, err = g.client.Notes.CreateMergeRequestNote(g.id, iid, createOpts, gitlab.WithContext(ctx))
+	return err
+}
+
 // Get returns the status of a given commit id in a Gitlab repository.
 func (g Gitlab) Get(commitID string, action string) (*Status, error) {
 	opts := gitlab.GetCommitStatusesOptions{
@@ -82,7 +152,23 @@ func (g Gitlab) Get(commitID string, action string) (*Status, error) {
 		}, nil
 	}
 
-	return nil, errors.New("No status found")
+	return nil, ErrStatusNotFound
+}
+
+// Revision returns the commit id that the given ref points to in a Gitlab repository.
+func (g Gitlab) Revision(ctx context.Context, ref string) (string, error) {
+	commit, _, err := g.client.Commits.GetCommit(g.id, ref, gitlab.WithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+
+	return commit.ID, nil
+}
+
+// Authenticate verifies that the project can be accessed with the configured token.
+func (g Gitlab) Authenticate(ctx context.Context) error {
+	_, _, err := g.client.Projects.GetProject(g.id, nil, gitlab.WithContext(ctx))
+	return err
 }
 
 // String returns the name of the struct.
@@ -6,25 +6,53 @@ import (
 
 // Mock implements a dummy notifier that doesn nothing.
 type Mock struct {
-	Events chan Event
+	Events     chan Event
+	Comments   chan Event
+	Statuses   map[string]*Status
+	SendErr    error
+	AuthErr    error
+	CommentErr error
+	// Revisions maps refs to the revision they resolve to
+	Revisions map[string]string
 }
 
 // NewMock creates and returns a Mock instance.
 func NewMock() *Mock {
 	return &Mock{
-		Events: make(chan Event, 100),
+		Events:   make(chan Event, 100),
+		Comments: make(chan Event, 100),
+		Statuses: map[string]*Status{},
 	}
 }
 
-// Send adds the event to the Events channel buffer.
+// Send adds the event to the Events channel buffer and returns SendErr.
 func (n *Mock) Send(ctx context.Context, e Event) error {
 	n.Events <- e
-	return nil
+	return n.SendErr
 }
 
-// Get always returns nil.
+// Comment adds the event to the Comments channel buffer and returns CommentErr.
+func (n *Mock) Comment(ctx context.Context, e Event) error {
+	n.Comments <- e
+	return n.CommentErr
+}
+
+// Get returns the status stored for the commit id and action, or nil if there is none.
 func (n *Mock) Get(commitID string, action string) (*Status, error) {
-	return nil, nil
+	return n.Statuses[commitID+"/"+action], nil
+}
+
+// Revision returns the revision set for the ref in Revisions, or the ref as is.
+func (n *Mock) Revision(ctx context.Context, ref string) (string, error) {
+	if rev, ok := n.Revisions[ref]; ok {
+		return rev, nil
+	}
+	return ref, nil
+}
+
+// Authenticate returns AuthErr.
+func (n *Mock) Authenticate(ctx context.Context) error {
+	return n.AuthErr
 }
 
 // String returns the name of the struct.
@@ -3,11 +3,16 @@ package notifier
 import (
 	"context"
 	"errors"
+
+	"github.com/fluxcd/flux/pkg/resource"
 )
 
 // StatusID is a project specific identifier to avoid conflicts in the commit status.
 const StatusID string = "flux-status"
 
+// ErrStatusNotFound is returned when a commit has no status for the requested action.
+var ErrStatusNotFound = errors.New("No status found")
+
 // EventType represents the different types of actions an event can occur for.
 type EventType string
 
@@ -17,6 +22,8 @@ const (
 	EventTypeSync EventType = "sync"
 	// EventTypeWorkload occurs when all workloads have started.
 	EventTypeWorkload EventType = "workload"
+	// EventTypeRelease occurs when Flux has committed or released an image update.
+	EventTypeRelease EventType = "release"
 )
 
 // EventState represents the different states an event can be in.
@@ -40,6 +47,14 @@ type Event struct {
 	Message  string
 	CommitID string
 	State    EventState
+	Errors   []ResourceError
+	Pending  []resource.ID
+	// Workloads has the detailed status of pending workloads, if known
+	Workloads []WorkloadStatus
+	// Changed has the resources changed by a sync, it is only set for the poller
+	Changed []resource.ID
+	// IncludedIn is the head commit of the sync that included the commit, it is empty for the head commit
+	IncludedIn string
 }
 
 // Status represents the current status of a commit id.
@@ -51,7 +66,10 @@ type Status struct {
 // Notifier is the interface that wraps the required methods to send events to a git provider.
 type Notifier interface {
 	Send(context.Context, Event) error
+	Comment(context.Context, Event) error
 	Get(string, string) (*Status, error)
+	Revision(context.Context, string) (string, error)
+	Authenticate(context.Context) error
 	String() string
 }
 
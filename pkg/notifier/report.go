@@ -0,0 +1,193 @@
+package notifier
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/fluxcd/flux/pkg/resource"
+)
+
+// ResourceError describes a resource that could not be applied during a sync.
+type ResourceError struct {
+	ID    resource.ID `json:"id"`
+	Path  string      `json:"path,omitempty"`
+	Error string      `json:"error"`
+}
+
+// WorkloadStatus describes a workload that was not healthy when polling ended.
+type WorkloadStatus struct {
+	ID        resource.ID `json:"id"`
+	Status    string      `json:"status"`
+	Desired   int32       `json:"desired"`
+	Ready     int32       `json:"ready"`
+	Updated   int32       `json:"updated"`
+	Available int32       `json:"available"`
+	Outdated  int32       `json:"outdated"`
+	Messages  []string    `json:"messages,omitempty"`
+	// Failed is set if the status of the workload failed the poll
+	Failed bool `json:"failed,omitempty"`
+}
+
+// Short returns the workload id followed by its ready pods, if it has any desired pods.
+func (w WorkloadStatus) Short() string {
+	if w.Desired == 0 {
+		return w.ID.String()
+	}
+	return fmt.Sprintf("%v (%d/%d ready)", w.ID, w.Ready, w.Desired)
+}
+
+// Summary returns the event message followed by as many of the failed resource
+// ids and short forms of unhealthy workloads as fits within limit characters, or
+// their amounts if none of them fits.
+func (e Event) Summary(limit int) string {
+	items := []string{}
+	for _, err := range sortedErrors(e.Errors) {
+		items = append(items, err.ID.String())
+	}
+	for _, w := range sortedWorkloads(e.Workloads) {
+		items = append(items, w.Short())
+	}
+
+	summary := e.Message
+	for i, item := range items {
+		sep := ": "
+		if i > 0 {
+			sep = ", "
+		}
+		candidate := summary + sep + item
+
+		suffix := ""
+		if remaining := len(items) - i - 1; remaining > 0 {
+			suffix = fmt.Sprintf(" and %d more", remaining)
+		}
+		if len(candidate+suffix) > limit {
+			if i == 0 {
+				summary = summary + itemCounts(len(e.Errors), len(e.Workloads))
+			} else {
+				summary = summary + fmt.Sprintf(" and %d more", len(items)-i)
+			}
+			break
+		}
+		summary = candidate
+	}
+
+	return truncate(summary, limit)
+}
+
+// Report returns the event message followed by every failed resource, its source
+// file and apply error, grouped by namespace, and any failed or still pending workloads
+// with their status, rollout and rollout messages when known.
+func (e Event) Report() string {
+	if len(e.Errors) == 0 && len(e.Pending) == 0 && len(e.Workloads) == 0 {
+		return e.Message
+	}
+
+	lines := []string{e.Message}
+	namespace := ""
+	for i, err := range sortedErrors(e.Errors) {
+		ns, kind, name := err.ID.Components()
+		if i == 0 || ns != namespace {
+			namespace = ns
+			lines = append(lines, "", fmt.Sprintf("Namespace %v:", ns))
+		}
+
+		line := fmt.Sprintf("- %v/%v", kind, name)
+		if err.Path != "" {
+			line = line + fmt.Sprintf(" (%v)", err.Path)
+		}
+		line = line + ": " + strings.TrimSpace(err.Error)
+		lines = append(lines, line)
+	}
+
+	if len(e.Workloads) > 0 {
+		group := ""
+		for _, w := range sortedWorkloads(e.Workloads) {
+			label := "Pending workloads:"
+			if w.Failed {
+				label = "Failed workloads:"
+			}
+			if label != group {
+				group = label
+				lines = append(lines, "", label)
+			}
+
+			line := fmt.Sprintf("- %v: %v", w.ID, w.Status)
+			if w.Desired > 0 {
+				line = line + fmt.Sprintf(", %d/%d ready, %d updated, %d available, %d outdated", w.Ready, w.Desired, w.Updated, w.Available, w.Outdated)
+			}
+			lines = append(lines, line)
+			for _, m := range w.Messages {
+				lines = append(lines, "  - "+strings.TrimSpace(m))
+			}
+		}
+	} else if len(e.Pending) > 0 {
+		lines = append(lines, "", "Pending workloads:")
+		for _, id := range e.Pending {
+			lines = append(lines, "- "+id.String())
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// itemCounts returns the amount of resources and workloads in parentheses, omitting any that are zero.
+func itemCounts(resources int, workloads int) string {
+	counts := []string{}
+	if resources > 0 {
+		counts = append(counts, fmt.Sprintf("%d resources", resources))
+	}
+	if workloads > 0 {
+		counts = append(counts, fmt.Sprintf("%d workloads", workloads))
+	}
+
+	return fmt.Sprintf(" (%v)", strings.Join(counts, ", "))
+}
+
+// sortedErrors returns a copy of the errors sorted by namespace and resource id.
+func sortedErrors(errs []ResourceError) []ResourceError {
+	result := make([]ResourceError, len(errs))
+	copy(result, errs)
+	sort.SliceStable(result, func(i, j int) bool {
+		iNs, _, _ := result[i].ID.Components()
+		jNs, _, _ := result[j].ID.Components()
+		if iNs != jNs {
+			return iNs < jNs
+		}
+		return result[i].ID.String() < result[j].ID.String()
+	})
+
+	return result
+}
+
+// sortedWorkloads returns a copy of the workloads sorted with failed workloads first and then by resource id.
+func sortedWorkloads(ww []WorkloadStatus) []WorkloadStatus {
+	result := make([]WorkloadStatus, len(ww))
+	copy(result, ww)
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Failed != result[j].Failed {
+			return result[i].Failed
+		}
+		return result[i].ID.String() < result[j].ID.String()
+	})
+
+	return result
+}
+
+// truncate shortens s to at most limit bytes, marking the cut with an ellipsis.
+func truncate(s string, limit int) string {
+	if limit <= 0 || len(s) <= limit {
+		return s
+	}
+	if limit <= 3 {
+		return s[:limit]
+	}
+
+	r := []rune(s[:limit-3])
+	// Drop a trailing rune if it was cut in half
+	if len(r) > 0 && r[len(r)-1] == utf8.RuneError {
+		r = r[:len(r)-1]
+	}
+	return string(r) + "..."
+}
@@ -0,0 +1,137 @@
+package notifier
+
+import (
+	"testing"
+
+	"github.com/fluxcd/flux/pkg/resource"
+	"github.com/onsi/gomega"
+)
+
+func testErrorEvent() Event {
+	return Event{
+		Message: "Failed applying 3 resources",
+		Errors: []ResourceError{
+			{
+				ID:    resource.MustParseID("foo:deployment/app"),
+				Path:  "foo/app.yaml",
+				Error: "invalid image\n",
+			},
+			{
+				ID:    resource.MustParseID("bar:service/app"),
+				Path:  "bar/app.yaml",
+				Error: "port is required",
+			},
+			{
+				ID:    resource.MustParseID("foo:configmap/app"),
+				Error: "data too long",
+			},
+		},
+	}
+}
+
+func TestSummaryWithoutErrors(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	e := Event{Message: "Succeeded"}
+	g.Expect(e.Summary(140)).Should(gomega.Equal("Succeeded"))
+}
+
+func TestSummaryFits(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	e := testErrorEvent()
+	g.Expect(e.Summary(140)).Should(gomega.Equal("Failed applying 3 resources: bar:service/app, foo:configmap/app, foo:deployment/app"))
+}
+
+func TestSummaryLimited(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	e := testErrorEvent()
+	s := e.Summary(60)
+	g.Expect(s).Should(gomega.Equal("Failed applying 3 resources: bar:service/app and 2 more"))
+	g.Expect(len(s)).Should(gomega.BeNumerically("<=", 60))
+}
+
+func TestSummaryNoItemFits(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	e := testErrorEvent()
+	g.Expect(e.Summary(45)).Should(gomega.Equal("Failed applying 3 resources (3 resources)"))
+}
+
+func TestSummaryTruncated(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	e := testErrorEvent()
+	g.Expect(e.Summary(20)).Should(gomega.Equal("Failed applying 3..."))
+}
+
+func TestReport(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	e := testErrorEvent()
+	expected := `Failed applying 3 resources
+
+Namespace bar:
+- service/app (bar/app.yaml): port is required
+
+Namespace foo:
+- configmap/app: data too long
+- deployment/app (foo/app.yaml): invalid image`
+	g.Expect(e.Report()).Should(gomega.Equal(expected))
+}
+
+func testTimeoutEvent() Event {
+	return Event{
+		Message: "Workload polling timed out",
+		Pending: []resource.ID{
+			resource.MustParseID("foo:deployment/web"),
+			resource.MustParseID("foo:deployment/api"),
+		},
+		Workloads: []WorkloadStatus{
+			{
+				ID:     resource.MustParseID("foo:deployment/web"),
+				Status: "updating",
+			},
+			{
+				ID:        resource.MustParseID("foo:deployment/api"),
+				Status:    "updating",
+				Desired:   3,
+				Ready:     1,
+				Updated:   2,
+				Available: 1,
+				Outdated:  1,
+				Messages:  []string{"ImagePullBackOff: image not found"},
+			},
+		},
+	}
+}
+
+func TestSummaryWorkloads(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	e := testTimeoutEvent()
+	g.Expect(e.Summary(140)).Should(gomega.Equal("Workload polling timed out: foo:deployment/api (1/3 ready), foo:deployment/web"))
+}
+
+func TestReportWorkloads(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	e := testTimeoutEvent()
+	expected := `Workload polling timed out
+
+Pending workloads:
+- foo:deployment/api: updating, 1/3 ready, 2 updated, 1 available, 1 outdated
+  - ImagePullBackOff: image not found
+- foo:deployment/web: updating`
+	g.Expect(e.Report()).Should(gomega.Equal(expected))
+}
+
+func TestReportFailedWorkloads(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	e := testTimeoutEvent()
+	e.Message = "1 workloads have failed"
+	e.Workloads[1].Status = "failed"
+	e.Workloads[1].Failed = true
+	expected := `1 workloads have failed
+
+Failed workloads:
+- foo:deployment/api: failed, 1/3 ready, 2 updated, 1 available, 1 outdated
+  - ImagePullBackOff: image not found
+
+Pending workloads:
+- foo:deployment/web: updating`
+	g.Expect(e.Report()).Should(gomega.Equal(expected))
+}
@@ -0,0 +1,73 @@
+package poller
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/fluxcd/flux/pkg/api/v6"
+)
+
+// IgnoreLabel removes a workload from health evaluation when set to "true" on the workload.
+const IgnoreLabel = "flux-status.xenit.io/ignore"
+
+// IgnorePolicy removes a workload from health evaluation when the workload manifest has the
+// annotation fluxcd.io/flux-status-ignore set to "true". Flux only exposes annotations with
+// its own prefix as policies, so other annotations are not visible to the poller.
+const IgnorePolicy = "flux-status-ignore"
+
+// Filter selects the workloads that are evaluated when polling. A pattern without a
+// colon or slash matches the kind of the workload, any other pattern is matched against
+// the whole resource id, for example "default:deployment/*".
+type Filter struct {
+	Include []string
+	Exclude []string
+}
+
+// NewFilter creates and returns a Filter instance, an empty include list includes all workloads.
+func NewFilter(include []string, exclude []string) (*Filter, error) {
+	for _, p := range append(append([]string{}, include...), exclude...) {
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("Invalid workload pattern %q: %w", p, err)
+		}
+	}
+
+	return &Filter{
+		Include: include,
+		Exclude: exclude,
+	}, nil
+}
+
+// Match returns true if the workload should be evaluated. Workloads ignored with Flux,
+// the ignore label or the ignore policy never match, and exclude patterns take precedence
+// over include patterns.
+func (f *Filter) Match(w v6.ControllerStatus) bool {
+	if w.Ignore || w.Labels[IgnoreLabel] == "true" || w.Policies[IgnorePolicy] == "true" {
+		return false
+	}
+	if f == nil {
+		return true
+	}
+	if matchAny(f.Exclude, w) {
+		return false
+	}
+
+	return len(f.Include) == 0 || matchAny(f.Include, w)
+}
+
+func matchAny(patterns []string, w v6.ControllerStatus) bool {
+	id := w.ID.String()
+	_, kind, _ := w.ID.Components()
+	for _, p := range patterns {
+		s := id
+		if !strings.ContainsAny(p, ":/") {
+			s = kind
+		}
+		// The patterns have been validated when creating the filter
+		if ok, _ := path.Match(p, s); ok {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,37 @@
+package poller
+
+import (
+	"testing"
+
+	v6 "github.com/fluxcd/flux/pkg/api/v6"
+	"github.com/fluxcd/flux/pkg/resource"
+	"github.com/onsi/gomega"
+)
+
+func TestFilterMatch(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	f, err := NewFilter([]string{"default:*/*", "cronjob"}, []string{"default:deployment/test-*"})
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+
+	g.Expect(f.Match(v6.ControllerStatus{ID: resource.MustParseID("default:deployment/app")})).Should(gomega.BeTrue())
+	g.Expect(f.Match(v6.ControllerStatus{ID: resource.MustParseID("jobs:cronjob/backup")})).Should(gomega.BeTrue())
+	g.Expect(f.Match(v6.ControllerStatus{ID: resource.MustParseID("other:deployment/app")})).Should(gomega.BeFalse())
+	g.Expect(f.Match(v6.ControllerStatus{ID: resource.MustParseID("default:deployment/test-tools")})).Should(gomega.BeFalse())
+}
+
+func TestFilterIgnored(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	var f *Filter
+	id := resource.MustParseID("default:deployment/app")
+
+	g.Expect(f.Match(v6.ControllerStatus{ID: id})).Should(gomega.BeTrue())
+	g.Expect(f.Match(v6.ControllerStatus{ID: id, Ignore: true})).Should(gomega.BeFalse())
+	g.Expect(f.Match(v6.ControllerStatus{ID: id, Labels: map[string]string{IgnoreLabel: "true"}})).Should(gomega.BeFalse())
+	g.Expect(f.Match(v6.ControllerStatus{ID: id, Policies: map[string]string{IgnorePolicy: "true"}})).Should(gomega.BeFalse())
+}
+
+func TestFilterInvalid(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	_, err := NewFilter([]string{"[default"}, nil)
+	g.Expect(err).Should(gomega.HaveOccurred())
+}
@@ -0,0 +1,109 @@
+package poller
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/fluxcd/flux/pkg/api/v6"
+	"sigs.k8s.io/yaml"
+)
+
+// DefaultKind is the kind whose rule is used for workloads of kinds without a rule.
+const DefaultKind = "*"
+
+// HealthRule decides the health of the workloads of a kind.
+type HealthRule struct {
+	// Healthy are the statuses of a healthy workload
+	Healthy []string `json:"healthy"`
+	// Failed are the statuses that fail the poll at once
+	Failed []string `json:"failed,omitempty"`
+	// MinReadyRatio makes a workload healthy when the ratio of ready to desired pods is reached, zero disables it
+	MinReadyRatio float64 `json:"minReadyRatio,omitempty"`
+}
+
+// HealthRules maps workload kinds to the rule deciding their health.
+type HealthRules map[string]HealthRule
+
+// health is the result of evaluating a workload.
+type health string
+
+const (
+	healthHealthy health = "healthy"
+	healthPending health = "pending"
+	healthFailed  health = "failed"
+)
+
+// DefaultHealthRules returns the rules used when no rules are configured, where
+// workloads of all kinds are healthy when their status is deployed or ready.
+func DefaultHealthRules() HealthRules {
+	return HealthRules{
+		DefaultKind: {
+			Healthy: []string{"deployed", "ready"},
+		},
+	}
+}
+
+// LoadHealthRules reads the rules from a YAML or JSON file mapping kinds to rules.
+// The default rule is used for kinds without a rule, unless the file overrides it.
+func LoadHealthRules(path string) (HealthRules, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	fileRules := HealthRules{}
+	if err := yaml.Unmarshal(b, &fileRules); err != nil {
+		return nil, fmt.Errorf("Could not parse health rules: %w", err)
+	}
+
+	rules := DefaultHealthRules()
+	for kind, rule := range fileRules {
+		if rule.MinReadyRatio < 0 || rule.MinReadyRatio > 1 {
+			return nil, fmt.Errorf("Min ready ratio of kind %v has to be between 0 and 1", kind)
+		}
+		rules[strings.ToLower(kind)] = rule
+	}
+
+	return rules, nil
+}
+
+// rule returns the rule of the kind, falling back to the default rule.
+func (r HealthRules) rule(kind string) HealthRule {
+	if rule, ok := r[kind]; ok {
+		return rule
+	}
+	if rule, ok := r[DefaultKind]; ok {
+		return rule
+	}
+
+	return DefaultHealthRules()[DefaultKind]
+}
+
+// evaluate returns the health of the workload. Statuses are compared case insensitively.
+func (r HealthRules) evaluate(w v6.ControllerStatus) health {
+	_, kind, _ := w.ID.Components()
+	rule := r.rule(kind)
+
+	if containsFold(rule.Failed, w.Status) {
+		return healthFailed
+	}
+	if containsFold(rule.Healthy, w.Status) {
+		return healthHealthy
+	}
+	desired := w.Rollout.Desired
+	if rule.MinReadyRatio > 0 && desired > 0 && float64(w.Rollout.Ready)/float64(desired) >= rule.MinReadyRatio {
+		return healthHealthy
+	}
+
+	return healthPending
+}
+
+func containsFold(ss []string, s string) bool {
+	for _, v := range ss {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+
+	return false
+}
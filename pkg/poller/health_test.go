@@ -0,0 +1,70 @@
+package poller
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	v6 "github.com/fluxcd/flux/pkg/api/v6"
+	"github.com/fluxcd/flux/pkg/cluster"
+	"github.com/fluxcd/flux/pkg/resource"
+	"github.com/onsi/gomega"
+)
+
+const testRules = `
+HelmRelease:
+  healthy: [deployed]
+  failed: [failed, rolled back]
+daemonset:
+  healthy: [ready]
+  minReadyRatio: 0.5
+`
+
+func TestLoadHealthRules(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	dir, err := ioutil.TempDir("", "rules")
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "rules.yaml")
+	g.Expect(ioutil.WriteFile(path, []byte(testRules), 0600)).Should(gomega.Succeed())
+
+	rules, err := LoadHealthRules(path)
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(rules).Should(gomega.HaveKey(DefaultKind))
+	g.Expect(rules["helmrelease"].Failed).Should(gomega.Equal([]string{"failed", "rolled back"}))
+	g.Expect(rules["daemonset"].MinReadyRatio).Should(gomega.Equal(0.5))
+}
+
+func TestLoadHealthRulesInvalidRatio(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	dir, err := ioutil.TempDir("", "rules")
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "rules.yaml")
+	g.Expect(ioutil.WriteFile(path, []byte("deployment:\n  minReadyRatio: 2\n"), 0600)).Should(gomega.Succeed())
+
+	_, err = LoadHealthRules(path)
+	g.Expect(err).Should(gomega.HaveOccurred())
+}
+
+func TestEvaluateHealth(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	rules := DefaultHealthRules()
+	rules["helmrelease"] = HealthRule{Healthy: []string{"deployed"}, Failed: []string{"failed", "rolled back"}}
+	rules["daemonset"] = HealthRule{Healthy: []string{"ready"}, MinReadyRatio: 0.5}
+
+	g.Expect(rules.evaluate(v6.ControllerStatus{ID: resource.MustParseID("ns:helmrelease/app"), Status: "Rolled Back"})).Should(gomega.Equal(healthFailed))
+	g.Expect(rules.evaluate(v6.ControllerStatus{ID: resource.MustParseID("ns:helmrelease/app"), Status: "ready"})).Should(gomega.Equal(healthPending))
+	g.Expect(rules.evaluate(v6.ControllerStatus{
+		ID:      resource.MustParseID("ns:daemonset/agent"),
+		Status:  "updating",
+		Rollout: cluster.RolloutStatus{Desired: 4, Ready: 2},
+	})).Should(gomega.Equal(healthHealthy))
+	g.Expect(rules.evaluate(v6.ControllerStatus{
+		ID:      resource.MustParseID("ns:daemonset/agent"),
+		Status:  "updating",
+		Rollout: cluster.RolloutStatus{Desired: 4, Ready: 1},
+	})).Should(gomega.Equal(healthPending))
+	g.Expect(rules.evaluate(v6.ControllerStatus{ID: resource.MustParseID("ns:deployment/app"), Status: "ready"})).Should(gomega.Equal(healthHealthy))
+}
@@ -3,89 +3,227 @@ package poller
 import (
 	"context"
 	"fmt"
-	"net/http"
-	"net/url"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/fluxcd/flux/pkg/api/v6"
-	transport "github.com/fluxcd/flux/pkg/http"
-	"github.com/fluxcd/flux/pkg/http/client"
 	"github.com/fluxcd/flux/pkg/resource"
 	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/label"
 
 	"github.com/xenitab/flux-status/pkg/flux"
+	"github.com/xenitab/flux-status/pkg/history"
+	"github.com/xenitab/flux-status/pkg/metrics"
 	"github.com/xenitab/flux-status/pkg/notifier"
+	"github.com/xenitab/flux-status/pkg/stream"
+	"github.com/xenitab/flux-status/pkg/tracing"
 )
 
+// HealthChecker lists the workloads in a namespace, or all namespaces if empty, with their health.
+// It is implemented by the Flux client.
+type HealthChecker interface {
+	ListServices(context.Context, string) ([]v6.ControllerStatus, error)
+}
+
+// Watcher is implemented by a HealthChecker that notifies when the health of any workload
+// may have changed. Workloads are then evaluated on every change instead of every interval.
+type Watcher interface {
+	Changes() <-chan struct{}
+}
+
 // Poller checks the health of workloads.
 type Poller struct {
 	Log      logr.Logger
 	Notifier notifier.Notifier
-	Events   <-chan string
+	Events   <-chan notifier.Event
 	Interval int
 	Timeout  int
 	Client   flux.Client
+	Broker   *stream.Broker
+	History  *history.Store
+	// ProgressInterval is the minimum duration in seconds between pending statuses sent while polling
+	ProgressInterval int
+	// Namespaces limits polling to the workloads in the namespaces, all namespaces are polled if empty
+	Namespaces []string
+	// ExcludeNamespaces are namespaces whose workloads are never polled
+	ExcludeNamespaces []string
+	// Filter selects the workloads that are evaluated, all workloads not ignored are evaluated if nil
+	Filter *Filter
+	// ChangedOnly limits polling to the workloads changed by the sync, if the sync event lists them
+	ChangedOnly bool
+	// Rules decide the health of the workloads, the default rules are used if nil
+	Rules HealthRules
+	// Checker lists the workloads with their health, the Flux client is used if nil
+	Checker HealthChecker
 
 	wg   sync.WaitGroup
 	quit chan struct{}
+	// done is closed when Start returns, after which no new polls are started
+	done chan struct{}
+	// stopCtx limits the final statuses sent by polls stopped by Stop
+	stopCtx context.Context
+	// handover is set when stopped by Handover, it is only read after quit is closed
+	handover bool
 }
 
-// NewPoller creates and returns a Poller instance.
-func NewPoller(l logr.Logger, n notifier.Notifier, e <-chan string, fAddr string, pi int, pt int) (*Poller, error) {
-	fluxURL, err := url.Parse(fmt.Sprintf("http://%v/api/flux", fAddr))
-	if err != nil {
-		return nil, err
-	}
+// job is a poll of the workloads for one or more commits.
+type job struct {
+	commitIDs []string
+	snapshot  resource.IDSet
+	start     time.Time
+	// deadline is zero if the poll never times out
+	deadline time.Time
+	// progress is the last pending status sent, and when it was sent
+	progress     string
+	progressSent time.Time
+	// supersededBy is the commit of the job that replaced this job, it is set before the job is canceled
+	supersededBy string
+	// changed limits the job to the resources changed by the sync, all workloads are polled if nil
+	changed resource.IDSet
+}
+
+// maxProgressWorkloads is the max number of pending workloads named in a progress message.
+const maxProgressWorkloads = 3
+
+// supersededTimeout limits sending the status of a superseded job, as its context is already canceled.
+const supersededTimeout = 10 * time.Second
+
+// DefaultProgressInterval is the default minimum duration in seconds between pending statuses.
+const DefaultProgressInterval = 30
 
+// NewPoller creates and returns a Poller instance.
+func NewPoller(l logr.Logger, n notifier.Notifier, e <-chan notifier.Event, c flux.Client, pi int, pt int) *Poller {
 	return &Poller{
-		Log:      l,
-		Events:   e,
-		Notifier: n,
-		Interval: pi,
-		Timeout:  pt,
-		Client:   client.New(http.DefaultClient, transport.NewAPIRouter(), fluxURL.String(), ""),
+		Log:              l,
+		Events:           e,
+		Notifier:         n,
+		Interval:         pi,
+		Timeout:          pt,
+		Client:           c,
+		ProgressInterval: DefaultProgressInterval,
 
 		wg:   sync.WaitGroup{},
 		quit: make(chan struct{}),
-	}, nil
+		done: make(chan struct{}),
+	}
 }
 
-// Start starts the poller and waits for new events.
+// Start starts the poller and waits for new events. A poll that was active
+// when the poller was last stopped is resumed first.
 func (p *Poller) Start() {
-	wg := sync.WaitGroup{}
+	defer close(p.done)
 	var pollCtx context.Context
 	var pollCancel context.CancelFunc = func() {}
+	var pollRelated chan string
+	var pollJob *job
+	// pollCommits are the commits reported by the running poll, the job itself is owned by the poll
+	var pollCommits []string
+	pollDone := make(chan struct{})
+	close(pollDone)
+	startPoll := func(j *job) {
+		select {
+		case <-pollDone:
+		default:
+			pollJob.supersededBy = j.commitIDs[0]
+		}
+		pollCancel()
+		pollJob = j
+		pollCommits = append([]string{}, j.commitIDs...)
+		pollCtx, pollCancel = context.WithCancel(context.Background())
+		pollRelated = make(chan string)
+		pollDone = make(chan struct{})
+		p.wg.Add(1)
+
+		go func(ctx context.Context, j *job, related <-chan string, done chan<- struct{}) {
+			defer p.wg.Done()
+			defer close(done)
+			commitID := j.commitIDs[0]
+			ctx, span := tracing.Start(ctx, "poll", commitID)
+			outcome, err := p.poll(ctx, j, related)
+			if err != nil {
+				p.Log.Error(err, "Error occured while polling")
+				outcome = metrics.PollError
+			}
+			metrics.ObservePoll(outcome, j.start)
+			span.SetAttributes(label.String("poll.outcome", outcome))
+			tracing.End(ctx, span, err)
+			p.record(commitID, outcome, j.start)
+			// Canceled polls are either replaced by a new poll or resumed after a restart,
+			// polls handed over are resumed by the new leader instead
+			if outcome != metrics.PollCanceled || p.handingOver() {
+				p.clear(commitID)
+			}
+		}(pollCtx, j, pollRelated, pollDone)
+	}
+
+	if j := p.resume(); j != nil {
+		startPoll(j)
+	}
 	for {
 		select {
 		case <-p.quit:
 			pollCancel()
 			return
-		case commitID := <-p.Events:
-			pollCancel()
-			pollCtx, pollCancel = context.WithCancel(context.Background())
-			wg.Add(1)
-
-			go func() {
-				defer wg.Done()
-				err := p.poll(pollCtx, commitID)
-				if err != nil {
-					p.Log.Error(err, "Error occured while polling")
+		case e := <-p.Events:
+			// Releases are applied as part of a sync so they are reported together with the running poll
+			if e.Type == notifier.EventTypeRelease {
+				select {
+				case pollRelated <- e.CommitID:
+					if !contains(pollCommits, e.CommitID) {
+						pollCommits = append(pollCommits, e.CommitID)
+					}
+					continue
+				case <-pollDone:
+				}
+			}
+
+			// The reconciler sends the synced commit again, which must not restart a resumed poll
+			select {
+			case <-pollDone:
+			default:
+				if contains(pollCommits, e.CommitID) {
+					p.Log.Info("Already polling commit", "commit-id", e.CommitID)
+					continue
 				}
-			}()
+			}
+
+			j := p.newJob(e.CommitID)
+			if p.ChangedOnly && len(e.Changed) > 0 {
+				j.changed = resource.IDSet{}
+				j.changed.Add(e.Changed)
+			}
+			// The changes of a replaced job have not become healthy yet, so they are polled by the new job
+			select {
+			case <-pollDone:
+			default:
+				if pollJob.changed == nil {
+					j.changed = nil
+				} else if j.changed != nil {
+					j.changed.Add(pollJob.changed.ToSlice())
+				}
+			}
+			startPoll(j)
 		}
 	}
 }
 
-// Stop gracefully cancels any running poll jobs and stops listening for new ones.
+// Stop stops listening for new events and cancels any running poll. The running poll
+// reports a final status for its commits before Stop returns, which is limited by the
+// deadline of the context. The status is pending if the poll is resumed after a restart.
 func (p *Poller) Stop(ctx context.Context) error {
+	p.stopCtx = ctx
+	close(p.quit)
+
 	c := make(chan struct{})
 	go func() {
 		defer close(c)
+		// Polls are only added by Start so it has to return before waiting
+		<-p.done
 		p.wg.Wait()
 	}()
 
-	close(p.quit)
 	for {
 		select {
 		case <-ctx.Done():
@@ -96,75 +234,519 @@ func (p *Poller) Stop(ctx context.Context) error {
 	}
 }
 
-func (p *Poller) poll(ctx context.Context, commitID string) error {
+// Handover stops the poller like Stop, but leaves the statuses of the running poll pending
+// for the replica that has taken over the leadership, whose reconciler resumes polling.
+func (p *Poller) Handover(ctx context.Context) error {
+	p.handover = true
+	return p.Stop(ctx)
+}
+
+// handingOver returns true if the poller is being stopped by Handover.
+func (p *Poller) handingOver() bool {
+	select {
+	case <-p.quit:
+		return p.handover
+	default:
+		return false
+	}
+}
+
+// poll waits for the workloads to become healthy and reports the result to the commits
+// of the job and any related commit received while polling. The outcome of the poll is returned.
+func (p *Poller) poll(ctx context.Context, j *job, related <-chan string) (string, error) {
+	commitID := j.commitIDs[0]
 	log := p.Log.WithValues("commit-id", commitID)
 	log.Info("Received event")
 
-	// Snap shot intitial workloads
-	workloads, err := p.Client.ListServices(ctx, "")
-	if err != nil {
-		return err
+	// Snap shot intitial workloads, a resumed poll already has its snapshot
+	var pending resource.IDSet
+	// workloads is the last known state of the workloads, a resumed poll does not know it until the first tick
+	var workloads []v6.ControllerStatus
+	message := "Waiting for workloads to be healthy"
+	// Flux may not be reachable yet when resuming a poll after a restart, so listing is retried until it succeeds once
+	resumed := j.snapshot != nil
+	if j.snapshot == nil {
+		var err error
+		workloads, err = p.listWorkloads(ctx)
+		if err != nil && ctx.Err() != nil {
+			// The poll has not been saved yet so it can't be resumed
+			p.canceled(j, resource.IDSet{}, false)
+			return metrics.PollCanceled, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		workloads = j.selectWorkloads(workloads)
+		j.snapshot = snapshotWorkloads(workloads)
+		pending = pendingWorkloads(workloads, p.healthRules())
+		message = progressMessage(workloads, pending)
+	} else {
+		pending = resource.IDSet{}
+		pending.Add(j.snapshot.ToSlice())
 	}
-	snap := snapshotWorkloads(workloads)
+	snap := j.snapshot
+	p.save(j)
+	metrics.PendingWorkloads.Set(float64(len(pending)))
+	p.progress(ctx, j, message, pending)
 
-	// Start polling workloads
-	tickCh := time.NewTicker(time.Duration(p.Interval) * time.Second)
-	timeoutCh := timeoutChannel(p.Timeout)
+	// Start polling workloads, or watching them if the health checker notifies about changes
+	var tickC <-chan time.Time
+	var changes <-chan struct{}
+	first := make(chan struct{}, 1)
+	if w, ok := p.healthChecker().(Watcher); ok {
+		changes = w.Changes()
+		first <- struct{}{}
+	} else {
+		tickCh := time.NewTicker(time.Duration(p.Interval) * time.Second)
+		defer tickCh.Stop()
+		tickC = tickCh.C
+	}
+	timeoutCh := timeoutChannel(j.deadline)
+	defer timeoutCh.Stop()
 	for {
 		select {
 		case <-ctx.Done():
-			log.Info("Poller stopped")
-			tickCh.Stop()
-			timeoutCh.Stop()
-			return nil
+			p.canceled(j, pending, p.History != nil)
+			return metrics.PollCanceled, nil
+		case relatedID := <-related:
+			log.Info("Reporting result to related commit", "related-commit-id", relatedID)
+			if !contains(j.commitIDs, relatedID) {
+				j.commitIDs = append(j.commitIDs, relatedID)
+				p.save(j)
+				p.sendProgress(ctx, []string{relatedID}, j.progress, pending)
+			}
+			continue
 		case <-timeoutCh.C:
 			log.Info("Poller timed out")
-			tickCh.Stop()
-			timeoutCh.Stop()
-			return p.Notifier.Send(ctx, notifier.Event{
-				Type:     notifier.EventTypeWorkload,
-				CommitID: commitID,
-				State:    notifier.EventStateFailed,
-				Message:  "Workload polling timed out",
+			metrics.PendingWorkloads.Set(0)
+			return metrics.PollTimeout, p.send(ctx, j.commitIDs, notifier.Event{
+				Type:      notifier.EventTypeWorkload,
+				State:     notifier.EventStateFailed,
+				Message:   "Workload polling timed out",
+				Pending:   pending.ToSlice(),
+				Workloads: workloadStatuses(workloads, pending, nil),
 			})
-		case <-tickCh.C:
-			log.Info("Poller tick")
+		case <-tickC:
+		case <-changes:
+		case <-first:
+		}
 
-			// Make a new snapshot of the workload state
-			newWorkloads, err := p.Client.ListServices(ctx, "")
-			if err != nil {
-				return err
-			}
-			newSnap := snapshotWorkloads(newWorkloads)
+		log.Info("Poller tick")
+		tickCtx, tickSpan := tracing.Start(ctx, "poll.tick", commitID)
 
-			// Make sure initial snapshot matches currently generated snapshot
-			if len(newSnap.Intersection(snap)) != len(snap) {
-				log.Info("Current workloads do not match workloads at sync")
+		// Make a new snapshot of the workload state
+		newWorkloads, err := p.listWorkloads(tickCtx)
+		if err != nil {
+			tracing.End(tickCtx, tickSpan, err)
+			// The canceled poll is reported on the next iteration
+			if ctx.Err() != nil {
 				continue
 			}
-
-			// Check if there are any pending workloads
-			pending := pendingWorkloads(newWorkloads)
-			if len(pending) > 0 {
-				log.Info("Waiting for workloads to be healthy", "pending", pending)
-				continue
+			if !resumed {
+				// The commits have a pending status that would otherwise never be resolved
+				metrics.PendingWorkloads.Set(0)
+				sendErr := p.send(ctx, j.commitIDs, notifier.Event{
+					Type:    notifier.EventTypeWorkload,
+					State:   notifier.EventStateFailed,
+					Message: "Could not list workloads",
+					Pending: pending.ToSlice(),
+				})
+				if sendErr != nil {
+					log.Error(sendErr, "Could not send failed status")
+				}
+				return "", err
 			}
+			log.Error(err, "Could not list workloads")
+			continue
+		}
+		resumed = false
+		newWorkloads = j.selectWorkloads(newWorkloads)
+		workloads = newWorkloads
+		newSnap := snapshotWorkloads(newWorkloads)
+
+		// Make sure initial snapshot matches currently generated snapshot
+		if len(newSnap.Intersection(snap)) != len(snap) {
+			log.Info("Current workloads do not match workloads at sync")
+			p.progress(ctx, j, "Current workloads do not match workloads at sync", pending)
+			tickSpan.End()
+			continue
+		}
 
-			// End poller as it has successfully completed
-			log.Info("All workloads are healthy")
-			err = p.Notifier.Send(ctx, notifier.Event{
-				Type:     notifier.EventTypeWorkload,
-				CommitID: commitID,
-				State:    notifier.EventStateSucceeded,
-				Message:  "All workloads have started successfully",
+		// Check if there are any pending workloads
+		pending = pendingWorkloads(newWorkloads, p.healthRules())
+		metrics.PendingWorkloads.Set(float64(len(pending)))
+		tickSpan.SetAttributes(label.Int("workloads.pending", len(pending)))
+		tickSpan.End()
+
+		// End poller at once if any workload has failed
+		if failed := failedWorkloads(newWorkloads, p.healthRules()); len(failed) > 0 {
+			log.Info("Workloads have failed", "failed", failed)
+			metrics.PendingWorkloads.Set(0)
+			return metrics.PollFailed, p.send(ctx, j.commitIDs, notifier.Event{
+				Type:      notifier.EventTypeWorkload,
+				State:     notifier.EventStateFailed,
+				Message:   fmt.Sprintf("%d workloads have failed", len(failed)),
+				Pending:   pending.ToSlice(),
+				Workloads: workloadStatuses(newWorkloads, pending, failed),
 			})
+		}
+		if len(pending) > 0 {
+			log.Info("Waiting for workloads to be healthy", "pending", pending)
+			p.progress(ctx, j, progressMessage(newWorkloads, pending), pending)
+			continue
+		}
+
+		// End poller as it has successfully completed
+		log.Info("All workloads are healthy")
+		return metrics.PollSucceeded, p.send(ctx, j.commitIDs, notifier.Event{
+			Type:    notifier.EventTypeWorkload,
+			State:   notifier.EventStateSucceeded,
+			Message: "All workloads have started successfully",
+		})
+	}
+}
+
+// canceled reports the canceled job as either superseded by a new job or interrupted by
+// a shutdown. On shutdown the commits are left pending if the job is resumed after a
+// restart, otherwise they are canceled.
+func (p *Poller) canceled(j *job, pending resource.IDSet, resumable bool) {
+	log := p.Log.WithValues("commit-id", j.commitIDs[0])
+	select {
+	case <-p.quit:
+		log.Info("Poller shutting down")
+	default:
+		p.superseded(j, pending)
+		return
+	}
+	if p.handingOver() {
+		log.Info("Leaving pending statuses to the new leader")
+		return
+	}
+
+	e := notifier.Event{
+		Type:    notifier.EventTypeWorkload,
+		State:   notifier.EventStateCanceled,
+		Message: "flux-status shutting down",
+		Pending: pending.ToSlice(),
+	}
+	if resumable {
+		e.State = notifier.EventStatePending
+		e.Message = "flux-status shutting down, polling resumes on restart"
+	}
+
+	if err := p.send(p.stopCtx, j.commitIDs, e); err != nil {
+		log.Error(err, "Could not send shutdown status")
+	}
+}
+
+// superseded reports the commits of the job as canceled by the job that replaced it.
+func (p *Poller) superseded(j *job, pending resource.IDSet) {
+	log := p.Log.WithValues("commit-id", j.commitIDs[0], "superseded-by", j.supersededBy)
+	log.Info("Poller superseded")
+	// The new job reports to the same commit, which would race with the canceled status
+	if j.supersededBy == "" || contains(j.commitIDs, j.supersededBy) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), supersededTimeout)
+	defer cancel()
+	err := p.send(ctx, j.commitIDs, notifier.Event{
+		Type:    notifier.EventTypeWorkload,
+		State:   notifier.EventStateCanceled,
+		Message: fmt.Sprintf("Superseded by %s", j.supersededBy),
+		Pending: pending.ToSlice(),
+	})
+	if err != nil {
+		log.Error(err, "Could not send superseded status")
+	}
+}
+
+// newJob returns a job polling the workloads for the commit.
+func (p *Poller) newJob(commitID string) *job {
+	j := &job{
+		commitIDs: []string{commitID},
+		start:     time.Now(),
+	}
+	if p.Timeout > 0 {
+		j.deadline = j.start.Add(time.Duration(p.Timeout) * time.Second)
+	}
+
+	return j
+}
+
+// resume returns the job that was active when the poller was stopped. If the deadline
+// of the job has passed it is reported as timed out instead, and nil is returned.
+func (p *Poller) resume() *job {
+	if p.History == nil {
+		return nil
+	}
+
+	poll, err := p.History.ActivePoll()
+	if err != nil {
+		p.Log.Error(err, "Could not read active poll")
+		return nil
+	}
+	if poll == nil || len(poll.CommitIDs) == 0 {
+		return nil
+	}
+
+	j := &job{
+		commitIDs: poll.CommitIDs,
+		snapshot:  p.parseIDs(poll.Snapshot),
+		start:     poll.Start,
+		deadline:  poll.Deadline,
+	}
+	if len(poll.Changed) > 0 {
+		j.changed = p.parseIDs(poll.Changed)
+	}
+
+	if !j.deadline.IsZero() && time.Now().After(j.deadline) {
+		p.Log.Info("Active poll timed out while stopped", "commit-id", j.commitIDs[0])
+		err := p.send(context.Background(), j.commitIDs, notifier.Event{
+			Type:    notifier.EventTypeWorkload,
+			State:   notifier.EventStateFailed,
+			Message: "Workload polling timed out",
+		})
+		if err != nil {
+			p.Log.Error(err, "Could not send timed out poll")
+		}
+		metrics.ObservePoll(metrics.PollTimeout, j.start)
+		p.record(j.commitIDs[0], metrics.PollTimeout, j.start)
+		p.clear(j.commitIDs[0])
+		return nil
+	}
+
+	p.Log.Info("Resuming active poll", "commit-id", j.commitIDs[0], "deadline", j.deadline)
+	return j
+}
+
+// healthChecker returns the configured health checker or the Flux client.
+func (p *Poller) healthChecker() HealthChecker {
+	if p.Checker == nil {
+		return p.Client
+	}
+
+	return p.Checker
+}
+
+// healthRules returns the configured rules or the default rules.
+func (p *Poller) healthRules() HealthRules {
+	if p.Rules == nil {
+		return DefaultHealthRules()
+	}
+
+	return p.Rules
+}
+
+// parseIDs returns the resource ids of an active poll, skipping any id that can not be parsed.
+func (p *Poller) parseIDs(ss []string) resource.IDSet {
+	result := resource.IDSet{}
+	for _, s := range ss {
+		id, err := resource.ParseID(s)
+		if err != nil {
+			p.Log.Error(err, "Could not parse resource id of active poll", "id", s)
+			continue
+		}
+		result.Add([]resource.ID{id})
+	}
+
+	return result
+}
+
+// save stores the job as the active poll if a store is configured.
+func (p *Poller) save(j *job) {
+	if p.History == nil {
+		return
+	}
+
+	snapshot := []string{}
+	for _, id := range j.snapshot.ToSlice() {
+		snapshot = append(snapshot, id.String())
+	}
+	var changed []string
+	for _, id := range j.changed.ToSlice() {
+		changed = append(changed, id.String())
+	}
+	err := p.History.SavePoll(history.Poll{
+		CommitIDs: j.commitIDs,
+		Snapshot:  snapshot,
+		Changed:   changed,
+		Start:     j.start,
+		Deadline:  j.deadline,
+	})
+	if err != nil {
+		p.Log.Error(err, "Could not save active poll")
+	}
+}
+
+// clear removes the active poll of the commit if a store is configured.
+func (p *Poller) clear(commitID string) {
+	if p.History == nil {
+		return
+	}
+
+	if err := p.History.ClearPoll(commitID); err != nil {
+		p.Log.Error(err, "Could not clear active poll")
+	}
+}
+
+// record records the outcome of the poll in the history if a store is configured.
+func (p *Poller) record(commitID string, outcome string, start time.Time) {
+	if p.History == nil {
+		return
+	}
+
+	err := p.History.Add(history.Entry{
+		Kind:     history.KindPoll,
+		Type:     string(notifier.EventTypeWorkload),
+		CommitID: commitID,
+		Message:  fmt.Sprintf("Polled workloads for %v", time.Since(start).Round(time.Second)),
+		Outcome:  outcome,
+	})
+	if err != nil {
+		p.Log.Error(err, "Could not record poll in history")
+	}
+}
+
+// progress publishes the poll progress to the broker if one is configured.
+func (p *Poller) progress(ctx context.Context, j *job, message string, pending resource.IDSet) {
+	if p.Broker != nil {
+		ids := []string{}
+		for _, id := range pending.ToSlice() {
+			ids = append(ids, id.String())
+		}
+		p.Broker.Publish(stream.Message{
+			Type:     stream.TypePoll,
+			CommitID: j.commitIDs[0],
+			State:    notifier.EventStatePending,
+			Message:  message,
+			Pending:  ids,
+		})
+	}
+
+	// Throttle the statuses so that the git provider is not flooded with updates
+	if message == j.progress {
+		return
+	}
+	if !j.progressSent.IsZero() && time.Since(j.progressSent) < time.Duration(p.ProgressInterval)*time.Second {
+		return
+	}
+	j.progress = message
+	j.progressSent = time.Now()
+	p.sendProgress(ctx, j.commitIDs, message, pending)
+}
+
+// sendProgress sends a pending workload status to the commits. Errors are only
+// logged as the poll should continue even if the git provider is unavailable.
+func (p *Poller) sendProgress(ctx context.Context, commitIDs []string, message string, pending resource.IDSet) {
+	err := p.send(ctx, commitIDs, notifier.Event{
+		Type:    notifier.EventTypeWorkload,
+		State:   notifier.EventStatePending,
+		Message: message,
+		Pending: pending.ToSlice(),
+	})
+	if err != nil {
+		p.Log.Error(err, "Could not send pending status", "commit-id", commitIDs[0])
+	}
+}
+
+// send sends the event for each of the commit ids.
+func (p *Poller) send(ctx context.Context, commitIDs []string, e notifier.Event) error {
+	for _, commitID := range commitIDs {
+		e.CommitID = commitID
+		if err := p.Notifier.Send(ctx, e); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func contains(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+// selectWorkloads returns the workloads changed by the sync of the job. Flux does not report
+// which workloads use a ConfigMap or Secret, so a changed ConfigMap or Secret selects every
+// workload in its namespace. All workloads are returned if the job is not limited.
+func (j *job) selectWorkloads(ww []v6.ControllerStatus) []v6.ControllerStatus {
+	if j.changed == nil {
+		return ww
+	}
+
+	namespaces := []string{}
+	for _, id := range j.changed.ToSlice() {
+		ns, kind, _ := id.Components()
+		if kind == "configmap" || kind == "secret" {
+			namespaces = append(namespaces, ns)
+		}
+	}
+
+	result := []v6.ControllerStatus{}
+	for _, w := range ww {
+		ns, _, _ := w.ID.Components()
+		if j.changed.Contains(w.ID) || contains(namespaces, ns) {
+			result = append(result, w)
+		}
+	}
+
+	return result
+}
+
+// listWorkloads returns the workloads in the polled namespaces. Each namespace is
+// listed concurrently so that polling a few namespaces in a large cluster stays fast.
+func (p *Poller) listWorkloads(ctx context.Context) ([]v6.ControllerStatus, error) {
+	if len(p.Namespaces) == 0 {
+		workloads, err := p.healthChecker().ListServices(ctx, "")
+		if err != nil {
+			return nil, err
+		}
+		return p.filterWorkloads(workloads), nil
+	}
+
+	wg := sync.WaitGroup{}
+	mu := sync.Mutex{}
+	workloads := []v6.ControllerStatus{}
+	var errs []error
+	for _, ns := range p.Namespaces {
+		wg.Add(1)
+		go func(ns string) {
+			defer wg.Done()
+			ww, err := p.healthChecker().ListServices(ctx, ns)
+			mu.Lock()
+			defer mu.Unlock()
 			if err != nil {
-				return err
+				errs = append(errs, fmt.Errorf("Could not list workloads in namespace %v: %w", ns, err))
+				return
 			}
+			workloads = append(workloads, ww...)
+		}(ns)
+	}
+	wg.Wait()
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
 
-			return nil
+	return p.filterWorkloads(workloads), nil
+}
+
+// filterWorkloads removes the workloads in excluded namespaces and the workloads not matching the Filter.
+func (p *Poller) filterWorkloads(ww []v6.ControllerStatus) []v6.ControllerStatus {
+	result := []v6.ControllerStatus{}
+	for _, w := range ww {
+		ns, _, _ := w.ID.Components()
+		if !contains(p.ExcludeNamespaces, ns) && p.Filter.Match(w) {
+			result = append(result, w)
 		}
 	}
+
+	return result
 }
 
 // snapshotWorkloads returns a list of resource ids created by flux
@@ -179,15 +761,68 @@ func snapshotWorkloads(ww []v6.ControllerStatus) resource.IDSet {
 	return result
 }
 
-// verifyServices returns any workload created by flux that is not ready
-func pendingWorkloads(ww []v6.ControllerStatus) resource.IDSet {
+// progressMessage returns a summary of the workloads that are ready and the ones still being waited on.
+func progressMessage(ww []v6.ControllerStatus, pending resource.IDSet) string {
+	total := 0
+	waiting := []string{}
+	for _, w := range ww {
+		if w.ReadOnly == v6.ReadOnlyMissing {
+			continue
+		}
+		total++
+		if !pending.Contains(w.ID) {
+			continue
+		}
+		if w.Rollout.Desired > 0 {
+			waiting = append(waiting, fmt.Sprintf("%s (%d/%d pods ready)", w.ID, w.Rollout.Ready, w.Rollout.Desired))
+		} else {
+			waiting = append(waiting, w.ID.String())
+		}
+	}
+
+	message := fmt.Sprintf("%d/%d workloads ready", total-len(waiting), total)
+	if len(waiting) == 0 {
+		return message
+	}
+	sort.Strings(waiting)
+	if len(waiting) > maxProgressWorkloads {
+		waiting = append(waiting[:maxProgressWorkloads], fmt.Sprintf("%d more", len(waiting)-maxProgressWorkloads))
+	}
+	return fmt.Sprintf("%s, waiting on %s", message, strings.Join(waiting, ", "))
+}
+
+// workloadStatuses returns the detailed status of the pending workloads, marking the failed workloads.
+func workloadStatuses(ww []v6.ControllerStatus, pending resource.IDSet, failed resource.IDSet) []notifier.WorkloadStatus {
+	result := []notifier.WorkloadStatus{}
+	for _, w := range ww {
+		if !pending.Contains(w.ID) {
+			continue
+		}
+		result = append(result, notifier.WorkloadStatus{
+			ID:        w.ID,
+			Status:    w.Status,
+			Desired:   w.Rollout.Desired,
+			Ready:     w.Rollout.Ready,
+			Updated:   w.Rollout.Updated,
+			Available: w.Rollout.Available,
+			Outdated:  w.Rollout.Outdated,
+			Messages:  w.Rollout.Messages,
+			Failed:    failed.Contains(w.ID),
+		})
+	}
+
+	return result
+}
+
+// pendingWorkloads returns any workload created by flux that is not healthy
+func pendingWorkloads(ww []v6.ControllerStatus, rules HealthRules) resource.IDSet {
 	result := resource.IDSet{}
 	for _, w := range ww {
 		if w.ReadOnly == v6.ReadOnlyMissing {
 			continue
 		}
 
-		if w.Status == "deployed" || w.Status == "ready" {
+		if rules.evaluate(w) == healthHealthy {
 			continue
 		}
 
@@ -197,9 +832,25 @@ func pendingWorkloads(ww []v6.ControllerStatus) resource.IDSet {
 	return result
 }
 
-func timeoutChannel(timeout int) *time.Timer {
-	timerCh := time.NewTimer(time.Duration(timeout) * time.Second)
-	if timeout == 0 {
+// failedWorkloads returns any workload created by flux with a status that fails the poll
+func failedWorkloads(ww []v6.ControllerStatus, rules HealthRules) resource.IDSet {
+	result := resource.IDSet{}
+	for _, w := range ww {
+		if w.ReadOnly == v6.ReadOnlyMissing {
+			continue
+		}
+
+		if rules.evaluate(w) == healthFailed {
+			result.Add([]resource.ID{w.ID})
+		}
+	}
+
+	return result
+}
+
+func timeoutChannel(deadline time.Time) *time.Timer {
+	timerCh := time.NewTimer(time.Until(deadline))
+	if deadline.IsZero() {
 		timerCh.Stop()
 	}
 
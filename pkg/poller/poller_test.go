@@ -4,12 +4,18 @@ import (
 	"context"
 	"crypto/rand"
 	"crypto/sha256"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	v6 "github.com/fluxcd/flux/pkg/api/v6"
+	"github.com/fluxcd/flux/pkg/cluster"
 	"github.com/fluxcd/flux/pkg/resource"
 	logr "github.com/go-logr/logr/testing"
 	"github.com/onsi/gomega"
@@ -18,6 +24,7 @@ import (
 	"go.uber.org/goleak"
 
 	"github.com/xenitab/flux-status/pkg/flux"
+	"github.com/xenitab/flux-status/pkg/history"
 )
 
 func TestVerifyReadyDeployment(t *testing.T) {
@@ -29,7 +36,7 @@ func TestVerifyReadyDeployment(t *testing.T) {
 			ReadOnly: "ReadOnlyMode",
 		},
 	}
-	res := pendingWorkloads(ww)
+	res := pendingWorkloads(ww, DefaultHealthRules())
 	g.Expect(res.String()).Should(gomega.Equal("{}"))
 }
 
@@ -42,7 +49,7 @@ func TestVerifyNotReadyDeployment(t *testing.T) {
 			ReadOnly: "ReadOnlyMode",
 		},
 	}
-	res := pendingWorkloads(ww)
+	res := pendingWorkloads(ww, DefaultHealthRules())
 	g.Expect(res.String()).Should(gomega.Equal("{namespace:deployment/resource-name}"))
 }
 
@@ -55,7 +62,7 @@ func TestVerifyReadyHelmRelease(t *testing.T) {
 			ReadOnly: "ReadOnlyMode",
 		},
 	}
-	res := pendingWorkloads(ww)
+	res := pendingWorkloads(ww, DefaultHealthRules())
 	g.Expect(res.String()).Should(gomega.Equal("{}"))
 }
 
@@ -68,7 +75,7 @@ func TestVerifyNotReadyHelmRelease(t *testing.T) {
 			ReadOnly: "ReadOnlyMode",
 		},
 	}
-	res := pendingWorkloads(ww)
+	res := pendingWorkloads(ww, DefaultHealthRules())
 	g.Expect(res.String()).Should(gomega.Equal("{namespace:helmrelease/resource-name}"))
 }
 
@@ -86,7 +93,7 @@ func TestVerifyHelmReleaseWithDeployment(t *testing.T) {
 			ReadOnly: "ReadOnlyMode",
 		},
 	}
-	res := pendingWorkloads(ww)
+	res := pendingWorkloads(ww, DefaultHealthRules())
 	g.Expect(res.String()).Should(gomega.Equal("{}"))
 }
 
@@ -107,7 +114,7 @@ func TestPollSuccessful(t *testing.T) {
 	g := gomega.NewGomegaWithT(t)
 
 	log := logr.TestLogger{T: t}
-	events := make(chan string)
+	events := make(chan notifier.Event)
 	noti := notifier.NewMock()
 	client := &flux.Mock{}
 
@@ -120,12 +127,13 @@ func TestPollSuccessful(t *testing.T) {
 		Client:   client,
 		wg:       sync.WaitGroup{},
 		quit:     make(chan struct{}),
+		done:     make(chan struct{}),
 	}
 	go poller.Start()
 
 	for i := 0; i < 5; i++ {
 		commitID := randHash()
-		events <- commitID
+		events <- notifier.Event{Type: notifier.EventTypeSync, CommitID: commitID}
 		g.Eventually(noti.Events, 5).Should(gomega.Receive(gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
 			"Type":     gomega.Equal(notifier.EventTypeWorkload),
 			"CommitID": gomega.Equal(commitID),
@@ -143,7 +151,7 @@ func TestPollTimeout(t *testing.T) {
 	g := gomega.NewGomegaWithT(t)
 
 	log := logr.TestLogger{T: t}
-	events := make(chan string)
+	events := make(chan notifier.Event)
 	noti := notifier.NewMock()
 	client := &flux.Mock{}
 
@@ -156,6 +164,7 @@ func TestPollTimeout(t *testing.T) {
 		Client:   client,
 		wg:       sync.WaitGroup{},
 		quit:     make(chan struct{}),
+		done:     make(chan struct{}),
 	}
 	go poller.Start()
 
@@ -168,11 +177,16 @@ func TestPollTimeout(t *testing.T) {
 	}
 
 	commitID := randHash()
-	events <- commitID
+	events <- notifier.Event{Type: notifier.EventTypeSync, CommitID: commitID}
 	g.Eventually(noti.Events, 12).Should(gomega.Receive(gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
 		"Type":     gomega.Equal(notifier.EventTypeWorkload),
 		"CommitID": gomega.Equal(commitID),
 		"State":    gomega.Equal(notifier.EventStateFailed),
+		"Pending":  gomega.ConsistOf(resource.MustParseID("namespace:helmrelease/resource-name")),
+		"Workloads": gomega.ConsistOf(gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
+			"ID":     gomega.Equal(resource.MustParseID("namespace:helmrelease/resource-name")),
+			"Status": gomega.Equal("failed"),
+		})),
 	})))
 	g.Consistently(noti.Events).ShouldNot(gomega.Receive())
 
@@ -185,7 +199,7 @@ func TestPollShutdown(t *testing.T) {
 	g := gomega.NewGomegaWithT(t)
 
 	log := logr.TestLogger{T: t}
-	events := make(chan string)
+	events := make(chan notifier.Event)
 	noti := notifier.NewMock()
 	client := &flux.Mock{}
 
@@ -198,6 +212,7 @@ func TestPollShutdown(t *testing.T) {
 		Client:   client,
 		wg:       sync.WaitGroup{},
 		quit:     make(chan struct{}),
+		done:     make(chan struct{}),
 	}
 	go poller.Start()
 
@@ -209,7 +224,681 @@ func TestPollShutdown(t *testing.T) {
 		},
 	}
 
-	events <- randHash()
+	commitID := randHash()
+	events <- notifier.Event{Type: notifier.EventTypeSync, CommitID: commitID}
+	err := poller.Stop(context.TODO())
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Eventually(noti.Events).Should(gomega.Receive(gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
+		"CommitID": gomega.Equal(commitID),
+		"Type":     gomega.Equal(notifier.EventTypeWorkload),
+		"State":    gomega.Equal(notifier.EventStateCanceled),
+		"Message":  gomega.Equal("flux-status shutting down"),
+	})))
+}
+
+func TestPollRelease(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	g := gomega.NewGomegaWithT(t)
+
+	log := logr.TestLogger{T: t}
+	events := make(chan notifier.Event)
+	noti := notifier.NewMock()
+	client := &flux.Mock{}
+
+	poller := Poller{
+		Log:      log,
+		Notifier: noti,
+		Events:   events,
+		Interval: 3,
+		Timeout:  10,
+		Client:   client,
+		wg:       sync.WaitGroup{},
+		quit:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go poller.Start()
+
+	commitID := randHash()
+	releaseID := randHash()
+	events <- notifier.Event{Type: notifier.EventTypeSync, CommitID: commitID}
+	events <- notifier.Event{Type: notifier.EventTypeRelease, CommitID: releaseID}
+	g.Eventually(noti.Events, 5).Should(gomega.Receive(gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
+		"Type":     gomega.Equal(notifier.EventTypeWorkload),
+		"CommitID": gomega.Equal(commitID),
+		"State":    gomega.Equal(notifier.EventStateSucceeded),
+	})))
+	g.Expect(noti.Events).Should(gomega.Receive(gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
+		"Type":     gomega.Equal(notifier.EventTypeWorkload),
+		"CommitID": gomega.Equal(releaseID),
+		"State":    gomega.Equal(notifier.EventStateSucceeded),
+	})))
+	g.Consistently(noti.Events).ShouldNot(gomega.Receive())
+
 	err := poller.Stop(context.TODO())
 	g.Expect(err).ShouldNot(gomega.HaveOccurred())
 }
+
+func TestPollReleaseResync(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	g := gomega.NewGomegaWithT(t)
+
+	events := make(chan notifier.Event)
+	noti := notifier.NewMock()
+	client := &flux.Mock{
+		Services: []v6.ControllerStatus{
+			{
+				ID:       resource.MustParseID("namespace:helmrelease/resource-name"),
+				Status:   "updating",
+				ReadOnly: "ReadOnlyMode",
+			},
+		},
+	}
+	poller := NewPoller(logr.TestLogger{T: t}, noti, events, client, 1, 0)
+	go poller.Start()
+
+	commitID := randHash()
+	releaseID := randHash()
+	events <- notifier.Event{Type: notifier.EventTypeSync, CommitID: commitID}
+	events <- notifier.Event{Type: notifier.EventTypeRelease, CommitID: releaseID}
+	// Syncs of commits reported by the running poll do not replace it
+	events <- notifier.Event{Type: notifier.EventTypeSync, CommitID: releaseID}
+	events <- notifier.Event{Type: notifier.EventTypeSync, CommitID: commitID}
+	g.Eventually(noti.Events, 5).Should(gomega.Receive(gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
+		"CommitID": gomega.Equal(releaseID),
+		"State":    gomega.Equal(notifier.EventStatePending),
+	})))
+	g.Consistently(noti.Events, 2).ShouldNot(gomega.Receive(gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
+		"State": gomega.Equal(notifier.EventStateCanceled),
+	})))
+
+	err := poller.Stop(context.TODO())
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+}
+
+func TestPollSuperseded(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	g := gomega.NewGomegaWithT(t)
+
+	events := make(chan notifier.Event)
+	noti := notifier.NewMock()
+	client := &flux.Mock{
+		Services: []v6.ControllerStatus{
+			{
+				ID:       resource.MustParseID("namespace:helmrelease/resource-name"),
+				Status:   "failed",
+				ReadOnly: "ReadOnlyMode",
+			},
+		},
+	}
+	poller := NewPoller(logr.TestLogger{T: t}, noti, events, client, 1, 0)
+	go poller.Start()
+
+	commitID := randHash()
+	events <- notifier.Event{Type: notifier.EventTypeSync, CommitID: commitID}
+	g.Eventually(noti.Events).Should(gomega.Receive(gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
+		"CommitID": gomega.Equal(commitID),
+		"State":    gomega.Equal(notifier.EventStatePending),
+	})))
+
+	newCommitID := randHash()
+	events <- notifier.Event{Type: notifier.EventTypeSync, CommitID: newCommitID}
+	g.Eventually(noti.Events).Should(gomega.Receive(gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
+		"Type":     gomega.Equal(notifier.EventTypeWorkload),
+		"CommitID": gomega.Equal(commitID),
+		"State":    gomega.Equal(notifier.EventStateCanceled),
+		"Message":  gomega.Equal("Superseded by " + newCommitID),
+		"Pending":  gomega.ConsistOf(resource.MustParseID("namespace:helmrelease/resource-name")),
+	})))
+
+	err := poller.Stop(context.TODO())
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+}
+
+func TestListWorkloads(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	client := &flux.Mock{
+		Services: []v6.ControllerStatus{
+			{ID: resource.MustParseID("a:deployment/app")},
+			{ID: resource.MustParseID("b:deployment/app")},
+			{ID: resource.MustParseID("c:deployment/app")},
+		},
+	}
+	poller := NewPoller(logr.TestLogger{T: t}, notifier.NewMock(), nil, client, 1, 0)
+
+	poller.ExcludeNamespaces = []string{"b"}
+	ww, err := poller.listWorkloads(context.TODO())
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(ww).Should(gomega.ConsistOf(client.Services[0], client.Services[2]))
+
+	poller.Namespaces = []string{"a", "b"}
+	ww, err = poller.listWorkloads(context.TODO())
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(ww).Should(gomega.ConsistOf(client.Services[0]))
+}
+
+func TestPollChangedOnly(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	g := gomega.NewGomegaWithT(t)
+
+	events := make(chan notifier.Event)
+	noti := notifier.NewMock()
+	client := &flux.Mock{
+		Services: []v6.ControllerStatus{
+			{
+				ID:       resource.MustParseID("a:deployment/broken"),
+				Status:   "failed",
+				ReadOnly: "ReadOnlyMode",
+			},
+			{
+				ID:       resource.MustParseID("b:deployment/app"),
+				Status:   "ready",
+				ReadOnly: "ReadOnlyMode",
+			},
+		},
+	}
+	poller := NewPoller(logr.TestLogger{T: t}, noti, events, client, 1, 0)
+	poller.ChangedOnly = true
+	go poller.Start()
+
+	commitID := randHash()
+	events <- notifier.Event{
+		Type:     notifier.EventTypeSync,
+		CommitID: commitID,
+		Changed:  []resource.ID{resource.MustParseID("b:deployment/app")},
+	}
+	g.Eventually(noti.Events, 5).Should(gomega.Receive(gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
+		"Type":     gomega.Equal(notifier.EventTypeWorkload),
+		"CommitID": gomega.Equal(commitID),
+		"State":    gomega.Equal(notifier.EventStateSucceeded),
+	})))
+
+	err := poller.Stop(context.TODO())
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+}
+
+func TestPollChangedOnlySuperseded(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	g := gomega.NewGomegaWithT(t)
+
+	events := make(chan notifier.Event)
+	noti := notifier.NewMock()
+	client := &flux.Mock{
+		Services: []v6.ControllerStatus{
+			{
+				ID:       resource.MustParseID("a:deployment/app"),
+				Status:   "updating",
+				ReadOnly: "ReadOnlyMode",
+			},
+			{
+				ID:       resource.MustParseID("b:deployment/app"),
+				Status:   "ready",
+				ReadOnly: "ReadOnlyMode",
+			},
+		},
+	}
+	poller := NewPoller(logr.TestLogger{T: t}, noti, events, client, 1, 0)
+	poller.ChangedOnly = true
+	go poller.Start()
+
+	commitID := randHash()
+	events <- notifier.Event{
+		Type:     notifier.EventTypeSync,
+		CommitID: commitID,
+		Changed:  []resource.ID{resource.MustParseID("a:deployment/app")},
+	}
+	g.Eventually(noti.Events, 5).Should(gomega.Receive(gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
+		"CommitID": gomega.Equal(commitID),
+		"State":    gomega.Equal(notifier.EventStatePending),
+	})))
+
+	// The workload changed by the superseded sync is still polled
+	newCommitID := randHash()
+	events <- notifier.Event{
+		Type:     notifier.EventTypeSync,
+		CommitID: newCommitID,
+		Changed:  []resource.ID{resource.MustParseID("b:deployment/app")},
+	}
+	g.Eventually(noti.Events, 5).Should(gomega.Receive(gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
+		"CommitID": gomega.Equal(newCommitID),
+		"State":    gomega.Equal(notifier.EventStatePending),
+		"Pending":  gomega.ConsistOf(resource.MustParseID("a:deployment/app")),
+	})))
+	g.Consistently(noti.Events, 2).ShouldNot(gomega.Receive(gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
+		"State": gomega.Equal(notifier.EventStateSucceeded),
+	})))
+
+	err := poller.Stop(context.TODO())
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+}
+
+func TestSelectWorkloads(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	ww := []v6.ControllerStatus{
+		{ID: resource.MustParseID("a:deployment/app")},
+		{ID: resource.MustParseID("a:deployment/other")},
+		{ID: resource.MustParseID("b:deployment/app")},
+		{ID: resource.MustParseID("c:deployment/app")},
+	}
+
+	j := &job{}
+	g.Expect(j.selectWorkloads(ww)).Should(gomega.Equal(ww))
+
+	j.changed = resource.IDSet{}
+	j.changed.Add([]resource.ID{
+		resource.MustParseID("a:deployment/app"),
+		resource.MustParseID("c:configmap/config"),
+	})
+	g.Expect(j.selectWorkloads(ww)).Should(gomega.ConsistOf(ww[0], ww[3]))
+}
+
+func TestPollFailed(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	g := gomega.NewGomegaWithT(t)
+
+	events := make(chan notifier.Event)
+	noti := notifier.NewMock()
+	client := &flux.Mock{
+		Services: []v6.ControllerStatus{
+			{
+				ID:       resource.MustParseID("namespace:helmrelease/resource-name"),
+				Status:   "rolled back",
+				ReadOnly: "ReadOnlyMode",
+			},
+		},
+	}
+	poller := NewPoller(logr.TestLogger{T: t}, noti, events, client, 1, 0)
+	poller.Rules = DefaultHealthRules()
+	poller.Rules["helmrelease"] = HealthRule{Healthy: []string{"deployed"}, Failed: []string{"failed", "rolled back"}}
+	go poller.Start()
+
+	commitID := randHash()
+	events <- notifier.Event{Type: notifier.EventTypeSync, CommitID: commitID}
+	g.Eventually(noti.Events, 5).Should(gomega.Receive(gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
+		"Type":     gomega.Equal(notifier.EventTypeWorkload),
+		"CommitID": gomega.Equal(commitID),
+		"State":    gomega.Equal(notifier.EventStateFailed),
+		"Message":  gomega.Equal("1 workloads have failed"),
+		"Workloads": gomega.ConsistOf(gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
+			"ID":     gomega.Equal(resource.MustParseID("namespace:helmrelease/resource-name")),
+			"Status": gomega.Equal("rolled back"),
+			"Failed": gomega.BeTrue(),
+		})),
+	})))
+	g.Consistently(noti.Events).ShouldNot(gomega.Receive())
+
+	err := poller.Stop(context.TODO())
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+}
+
+// watchChecker is a HealthChecker that notifies about changes.
+type watchChecker struct {
+	*flux.Mock
+	changes chan struct{}
+}
+
+func (w watchChecker) Changes() <-chan struct{} {
+	return w.changes
+}
+
+func TestPollWatch(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	g := gomega.NewGomegaWithT(t)
+
+	events := make(chan notifier.Event)
+	noti := notifier.NewMock()
+	checker := watchChecker{
+		Mock: &flux.Mock{
+			Services: []v6.ControllerStatus{
+				{
+					ID:     resource.MustParseID("namespace:deployment/app"),
+					Status: "ready",
+				},
+			},
+		},
+		changes: make(chan struct{}),
+	}
+	// The interval is never reached as the workloads are evaluated when polling starts
+	poller := NewPoller(logr.TestLogger{T: t}, noti, events, &flux.Mock{}, 3600, 0)
+	poller.Checker = checker
+	go poller.Start()
+
+	commitID := randHash()
+	events <- notifier.Event{Type: notifier.EventTypeSync, CommitID: commitID}
+	g.Eventually(noti.Events, 5).Should(gomega.Receive(gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
+		"Type":     gomega.Equal(notifier.EventTypeWorkload),
+		"CommitID": gomega.Equal(commitID),
+		"State":    gomega.Equal(notifier.EventStateSucceeded),
+	})))
+
+	err := poller.Stop(context.TODO())
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+}
+
+func TestPollProgress(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	g := gomega.NewGomegaWithT(t)
+
+	events := make(chan notifier.Event)
+	noti := notifier.NewMock()
+	client := &flux.Mock{
+		Services: []v6.ControllerStatus{
+			{
+				ID:       resource.MustParseID("namespace:deployment/api"),
+				Status:   "updating",
+				ReadOnly: "ReadOnlyMode",
+				Rollout:  cluster.RolloutStatus{Desired: 3, Ready: 1},
+			},
+			{
+				ID:       resource.MustParseID("namespace:deployment/web"),
+				Status:   "ready",
+				ReadOnly: "ReadOnlyMode",
+			},
+		},
+	}
+	poller := NewPoller(logr.TestLogger{T: t}, noti, events, client, 1, 0)
+	go poller.Start()
+
+	commitID := randHash()
+	events <- notifier.Event{Type: notifier.EventTypeSync, CommitID: commitID}
+	g.Eventually(noti.Events).Should(gomega.Receive(gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
+		"Type":     gomega.Equal(notifier.EventTypeWorkload),
+		"CommitID": gomega.Equal(commitID),
+		"State":    gomega.Equal(notifier.EventStatePending),
+		"Message":  gomega.Equal("1/2 workloads ready, waiting on namespace:deployment/api (1/3 pods ready)"),
+		"Pending":  gomega.ConsistOf(resource.MustParseID("namespace:deployment/api")),
+	})))
+	// Unchanged progress is not sent again
+	g.Consistently(noti.Events, 3).ShouldNot(gomega.Receive())
+
+	err := poller.Stop(context.TODO())
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+}
+
+func TestProgressMessage(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	ww := []v6.ControllerStatus{}
+	pending := resource.IDSet{}
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		id := resource.MustParseID("namespace:deployment/" + name)
+		ww = append(ww, v6.ControllerStatus{ID: id, Status: "updating"})
+		pending.Add([]resource.ID{id})
+	}
+	ww = append(ww, v6.ControllerStatus{ID: resource.MustParseID("namespace:deployment/f"), Status: "ready"})
+
+	g.Expect(progressMessage(ww, pending)).To(gomega.Equal("1/6 workloads ready, waiting on namespace:deployment/a, namespace:deployment/b, namespace:deployment/c, 2 more"))
+	g.Expect(progressMessage(ww[5:], resource.IDSet{})).To(gomega.Equal("1/1 workloads ready"))
+}
+
+func testHistory(t *testing.T) (*history.Store, func()) {
+	dir, err := ioutil.TempDir("", "poller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := history.Open(filepath.Join(dir, "history.db"), "dev", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return store, func() {
+		store.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestPollResume(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	g := gomega.NewGomegaWithT(t)
+
+	store, cleanup := testHistory(t)
+	defer cleanup()
+	commitID := randHash()
+	releaseID := randHash()
+	err := store.SavePoll(history.Poll{
+		CommitIDs: []string{commitID, releaseID},
+		Snapshot:  []string{"namespace:helmrelease/resource-name"},
+		Start:     time.Now().Add(-time.Minute),
+		Deadline:  time.Now().Add(time.Minute),
+	})
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+
+	noti := notifier.NewMock()
+	client := &flux.Mock{
+		Services: []v6.ControllerStatus{
+			{
+				ID:       resource.MustParseID("namespace:helmrelease/resource-name"),
+				Status:   "deployed",
+				ReadOnly: "ReadOnlyMode",
+			},
+		},
+	}
+	poller := NewPoller(logr.TestLogger{T: t}, noti, make(chan notifier.Event), client, 1, 10)
+	poller.History = store
+	go poller.Start()
+
+	for _, id := range []string{commitID, releaseID} {
+		g.Eventually(noti.Events, 5).Should(gomega.Receive(gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
+			"Type":     gomega.Equal(notifier.EventTypeWorkload),
+			"CommitID": gomega.Equal(id),
+			"State":    gomega.Equal(notifier.EventStateSucceeded),
+		})))
+	}
+	g.Eventually(func() (*history.Poll, error) { return store.ActivePoll() }).Should(gomega.BeNil())
+
+	err = poller.Stop(context.TODO())
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+}
+
+func TestPollResumeSameCommit(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	g := gomega.NewGomegaWithT(t)
+
+	store, cleanup := testHistory(t)
+	defer cleanup()
+	commitID := randHash()
+	start := time.Now().Add(-time.Minute).UTC().Truncate(time.Second)
+	err := store.SavePoll(history.Poll{
+		CommitIDs: []string{commitID},
+		Snapshot:  []string{"namespace:helmrelease/resource-name"},
+		Start:     start,
+		Deadline:  time.Now().Add(time.Minute),
+	})
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+
+	events := make(chan notifier.Event)
+	noti := notifier.NewMock()
+	client := &flux.Mock{
+		Services: []v6.ControllerStatus{
+			{
+				ID:       resource.MustParseID("namespace:helmrelease/resource-name"),
+				Status:   "updating",
+				ReadOnly: "ReadOnlyMode",
+			},
+		},
+	}
+	poller := NewPoller(logr.TestLogger{T: t}, noti, events, client, 1, 10)
+	poller.History = store
+	go poller.Start()
+	g.Eventually(noti.Events, 5).Should(gomega.Receive(gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
+		"CommitID": gomega.Equal(commitID),
+		"State":    gomega.Equal(notifier.EventStatePending),
+	})))
+
+	// The reconciler sends the synced commit again on startup
+	events <- notifier.Event{Type: notifier.EventTypeSync, CommitID: commitID}
+	g.Consistently(noti.Events, 2).ShouldNot(gomega.Receive())
+	active, err := store.ActivePoll()
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(active.Start.Equal(start)).Should(gomega.BeTrue())
+
+	err = poller.Stop(context.TODO())
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+}
+
+// failingChecker is a HealthChecker that fails after listing the workloads once.
+type failingChecker struct {
+	*flux.Mock
+	calls *int32
+}
+
+func (f failingChecker) ListServices(ctx context.Context, namespace string) ([]v6.ControllerStatus, error) {
+	if atomic.AddInt32(f.calls, 1) > 1 {
+		return nil, errors.New("connection refused")
+	}
+	return f.Mock.ListServices(ctx, namespace)
+}
+
+func TestPollListError(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	g := gomega.NewGomegaWithT(t)
+
+	store, cleanup := testHistory(t)
+	defer cleanup()
+	events := make(chan notifier.Event)
+	noti := notifier.NewMock()
+	checker := failingChecker{
+		Mock: &flux.Mock{
+			Services: []v6.ControllerStatus{
+				{
+					ID:       resource.MustParseID("namespace:helmrelease/resource-name"),
+					Status:   "updating",
+					ReadOnly: "ReadOnlyMode",
+				},
+			},
+		},
+		calls: new(int32),
+	}
+	poller := NewPoller(logr.TestLogger{T: t}, noti, events, &flux.Mock{}, 1, 10)
+	poller.History = store
+	poller.Checker = checker
+	go poller.Start()
+
+	commitID := randHash()
+	events <- notifier.Event{Type: notifier.EventTypeSync, CommitID: commitID}
+	g.Eventually(noti.Events, 5).Should(gomega.Receive(gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
+		"CommitID": gomega.Equal(commitID),
+		"State":    gomega.Equal(notifier.EventStatePending),
+	})))
+
+	// Only resumed polls retry listing the workloads
+	g.Eventually(noti.Events, 5).Should(gomega.Receive(gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
+		"Type":     gomega.Equal(notifier.EventTypeWorkload),
+		"CommitID": gomega.Equal(commitID),
+		"State":    gomega.Equal(notifier.EventStateFailed),
+		"Message":  gomega.Equal("Could not list workloads"),
+		"Pending":  gomega.ConsistOf(resource.MustParseID("namespace:helmrelease/resource-name")),
+	})))
+	g.Eventually(func() (*history.Poll, error) { return store.ActivePoll() }, 5).Should(gomega.BeNil())
+	g.Expect(atomic.LoadInt32(checker.calls)).Should(gomega.Equal(int32(2)))
+
+	err := poller.Stop(context.TODO())
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+}
+
+func TestPollResumeTimedOut(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	g := gomega.NewGomegaWithT(t)
+
+	store, cleanup := testHistory(t)
+	defer cleanup()
+	commitID := randHash()
+	err := store.SavePoll(history.Poll{
+		CommitIDs: []string{commitID},
+		Start:     time.Now().Add(-time.Hour),
+		Deadline:  time.Now().Add(-time.Minute),
+	})
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+
+	noti := notifier.NewMock()
+	poller := NewPoller(logr.TestLogger{T: t}, noti, make(chan notifier.Event), &flux.Mock{}, 1, 10)
+	poller.History = store
+	go poller.Start()
+
+	g.Eventually(noti.Events, 5).Should(gomega.Receive(gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
+		"Type":     gomega.Equal(notifier.EventTypeWorkload),
+		"CommitID": gomega.Equal(commitID),
+		"State":    gomega.Equal(notifier.EventStateFailed),
+	})))
+	g.Consistently(noti.Events).ShouldNot(gomega.Receive())
+	active, err := store.ActivePoll()
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(active).Should(gomega.BeNil())
+
+	err = poller.Stop(context.TODO())
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+}
+
+func TestPollShutdownKeepsActivePoll(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	g := gomega.NewGomegaWithT(t)
+
+	store, cleanup := testHistory(t)
+	defer cleanup()
+	client := &flux.Mock{
+		Services: []v6.ControllerStatus{
+			{
+				ID:       resource.MustParseID("namespace:helmrelease/resource-name"),
+				Status:   "failed",
+				ReadOnly: "ReadOnlyMode",
+			},
+		},
+	}
+	events := make(chan notifier.Event)
+	noti := notifier.NewMock()
+	poller := NewPoller(logr.TestLogger{T: t}, noti, events, client, 1, 10)
+	poller.History = store
+	go poller.Start()
+
+	commitID := randHash()
+	events <- notifier.Event{Type: notifier.EventTypeSync, CommitID: commitID}
+	g.Eventually(func() (*history.Poll, error) { return store.ActivePoll() }).ShouldNot(gomega.BeNil())
+	err := poller.Stop(context.TODO())
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Eventually(noti.Events).Should(gomega.Receive(gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
+		"CommitID": gomega.Equal(commitID),
+		"State":    gomega.Equal(notifier.EventStatePending),
+		"Message":  gomega.Equal("flux-status shutting down, polling resumes on restart"),
+		"Pending":  gomega.ConsistOf(resource.MustParseID("namespace:helmrelease/resource-name")),
+	})))
+
+	g.Consistently(func() (*history.Poll, error) { return store.ActivePoll() }).Should(gstruct.PointTo(gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
+		"CommitIDs": gomega.Equal([]string{commitID}),
+		"Snapshot":  gomega.ConsistOf("namespace:helmrelease/resource-name"),
+	})))
+}
+
+func TestPollHandover(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	g := gomega.NewGomegaWithT(t)
+
+	store, cleanup := testHistory(t)
+	defer cleanup()
+	client := &flux.Mock{
+		Services: []v6.ControllerStatus{
+			{
+				ID:       resource.MustParseID("namespace:helmrelease/resource-name"),
+				Status:   "updating",
+				ReadOnly: "ReadOnlyMode",
+			},
+		},
+	}
+	events := make(chan notifier.Event)
+	noti := notifier.NewMock()
+	poller := NewPoller(logr.TestLogger{T: t}, noti, events, client, 1, 10)
+	poller.History = store
+	go poller.Start()
+
+	commitID := randHash()
+	events <- notifier.Event{Type: notifier.EventTypeSync, CommitID: commitID}
+	g.Eventually(noti.Events, 5).Should(gomega.Receive(gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
+		"CommitID": gomega.Equal(commitID),
+		"State":    gomega.Equal(notifier.EventStatePending),
+	})))
+
+	// The status is left pending for the new leader
+	err := poller.Handover(context.TODO())
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Consistently(noti.Events).ShouldNot(gomega.Receive())
+	active, err := store.ActivePoll()
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(active).Should(gomega.BeNil())
+}
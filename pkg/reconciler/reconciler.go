@@ -0,0 +1,170 @@
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"github.com/xenitab/flux-status/pkg/flux"
+	"github.com/xenitab/flux-status/pkg/notifier"
+)
+
+// Reconciler finishes statuses left incomplete by a previous flux-status process.
+type Reconciler struct {
+	Log      logr.Logger
+	Notifier notifier.Notifier
+	Client   flux.Client
+	Events   chan<- notifier.Event
+	Interval int
+}
+
+// NewReconciler creates and returns a Reconciler instance.
+func NewReconciler(l logr.Logger, n notifier.Notifier, c flux.Client, e chan<- notifier.Event, ri int) *Reconciler {
+	return &Reconciler{
+		Log:      l,
+		Notifier: n,
+		Client:   c,
+		Events:   e,
+		Interval: ri,
+	}
+}
+
+// Reconcile finishes the statuses of the revision currently synced by Flux.
+// It retries until it succeeds or the context is done, as Flux may not be
+// reachable yet when flux-status starts.
+func (r *Reconciler) Reconcile(ctx context.Context) error {
+	for {
+		err := r.reconcile(ctx)
+		if err == nil {
+			return nil
+		}
+		r.Log.Error(err, "Could not reconcile statuses")
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(r.Interval) * time.Second):
+		}
+	}
+}
+
+func (r *Reconciler) reconcile(ctx context.Context) error {
+	commitID, err := r.syncedRevision(ctx)
+	if err != nil {
+		return err
+	}
+	if commitID == "" {
+		r.Log.Info("Flux has unsynced commits, skipping reconciliation")
+		return nil
+	}
+	log := r.Log.WithValues("commit-id", commitID)
+
+	syncStatus, err := r.status(commitID, notifier.EventTypeSync)
+	if err != nil {
+		return err
+	}
+	if syncStatus != nil && syncStatus.State == notifier.EventStateFailed {
+		log.Info("Sync has failed, no workload status expected")
+		return nil
+	}
+	if !completed(syncStatus) {
+		log.Info("Canceling incomplete sync status")
+		err := r.Notifier.Send(ctx, notifier.Event{
+			Type:     notifier.EventTypeSync,
+			CommitID: commitID,
+			State:    notifier.EventStateCanceled,
+			Message:  "Sync result was lost when flux-status restarted",
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	workloadStatus, err := r.status(commitID, notifier.EventTypeWorkload)
+	if err != nil {
+		return err
+	}
+	if completed(workloadStatus) {
+		log.Info("Statuses are already complete")
+		return nil
+	}
+
+	// Cancel the workload status if there is no poller to finish it
+	if r.Events == nil {
+		log.Info("Canceling incomplete workload status")
+		return r.Notifier.Send(ctx, notifier.Event{
+			Type:     notifier.EventTypeWorkload,
+			CommitID: commitID,
+			State:    notifier.EventStateCanceled,
+			Message:  "Workload polling was interrupted when flux-status restarted",
+		})
+	}
+
+	log.Info("Restarting workload polling")
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case r.Events <- notifier.Event{Type: notifier.EventTypeSync, CommitID: commitID}:
+		return nil
+	}
+}
+
+// syncedRevision returns the commit id of the branch head if Flux has synced it.
+// An empty string is returned if Flux still has commits left to sync, as a new
+// sync event will be received for those. The head is resolved by the git provider,
+// which may be ahead of the revisions Flux has fetched, so the head itself has to
+// be synced by Flux as well.
+func (r *Reconciler) syncedRevision(ctx context.Context) (string, error) {
+	config, err := r.Client.GitRepoConfig(ctx, false)
+	if err != nil {
+		return "", err
+	}
+	branch := config.Remote.Branch
+	if branch == "" {
+		return "", errors.New("Flux has no git branch configured")
+	}
+
+	unsynced, err := r.Client.SyncStatus(ctx, branch)
+	if err != nil {
+		return "", err
+	}
+	if len(unsynced) > 0 {
+		return "", nil
+	}
+
+	commitID, err := r.Notifier.Revision(ctx, branch)
+	if err != nil {
+		return "", err
+	}
+	// Flux does not know revisions it has not fetched yet
+	unsynced, err = r.Client.SyncStatus(ctx, commitID)
+	if err != nil {
+		r.Log.Error(err, "Could not get sync status of branch head", "commit-id", commitID)
+		return "", nil
+	}
+	if len(unsynced) > 0 {
+		return "", nil
+	}
+
+	return commitID, nil
+}
+
+// status returns the status for the commit id and event type, or nil if none has been set.
+func (r *Reconciler) status(commitID string, t notifier.EventType) (*notifier.Status, error) {
+	status, err := r.Notifier.Get(commitID, string(t))
+	if errors.Is(err, notifier.ErrStatusNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return status, nil
+}
+
+// completed returns true if the status has reached a final state.
+func completed(s *notifier.Status) bool {
+	return s != nil && s.State != notifier.EventStatePending
+}
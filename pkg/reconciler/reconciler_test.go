@@ -0,0 +1,128 @@
+package reconciler
+
+import (
+	"context"
+	"testing"
+
+	v6 "github.com/fluxcd/flux/pkg/api/v6"
+	logr "github.com/go-logr/logr/testing"
+	"github.com/onsi/gomega"
+	"github.com/onsi/gomega/gstruct"
+
+	"github.com/xenitab/flux-status/pkg/flux"
+	"github.com/xenitab/flux-status/pkg/notifier"
+)
+
+func newClient(commitID string) *flux.Mock {
+	return &flux.Mock{
+		GitConfig: v6.GitConfig{
+			Remote: v6.GitRemoteConfig{
+				Branch: commitID,
+			},
+		},
+	}
+}
+
+func TestReconcileCompleted(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	commitID := "foobar"
+	noti := notifier.NewMock()
+	noti.Statuses[commitID+"/sync"] = &notifier.Status{State: notifier.EventStateSucceeded}
+	noti.Statuses[commitID+"/workload"] = &notifier.Status{State: notifier.EventStateSucceeded}
+	events := make(chan notifier.Event, 1)
+
+	r := NewReconciler(logr.TestLogger{T: t}, noti, newClient(commitID), events, 1)
+	err := r.Reconcile(context.TODO())
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(events).ShouldNot(gomega.Receive())
+	g.Expect(noti.Events).ShouldNot(gomega.Receive())
+}
+
+func TestReconcilePendingWorkload(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	commitID := "foobar"
+	noti := notifier.NewMock()
+	noti.Statuses[commitID+"/sync"] = &notifier.Status{State: notifier.EventStateSucceeded}
+	noti.Statuses[commitID+"/workload"] = &notifier.Status{State: notifier.EventStatePending}
+	events := make(chan notifier.Event, 1)
+
+	r := NewReconciler(logr.TestLogger{T: t}, noti, newClient(commitID), events, 1)
+	err := r.Reconcile(context.TODO())
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(events).Should(gomega.Receive(gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
+		"Type":     gomega.Equal(notifier.EventTypeSync),
+		"CommitID": gomega.Equal(commitID),
+	})))
+	g.Expect(noti.Events).ShouldNot(gomega.Receive())
+}
+
+func TestReconcileMissingStatuses(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	commitID := "foobar"
+	noti := notifier.NewMock()
+
+	r := NewReconciler(logr.TestLogger{T: t}, noti, newClient(commitID), nil, 1)
+	err := r.Reconcile(context.TODO())
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(noti.Events).Should(gomega.Receive(gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
+		"Type":     gomega.Equal(notifier.EventTypeSync),
+		"CommitID": gomega.Equal(commitID),
+		"State":    gomega.Equal(notifier.EventStateCanceled),
+	})))
+	g.Expect(noti.Events).Should(gomega.Receive(gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
+		"Type":     gomega.Equal(notifier.EventTypeWorkload),
+		"CommitID": gomega.Equal(commitID),
+		"State":    gomega.Equal(notifier.EventStateCanceled),
+	})))
+}
+
+func TestReconcileFailedSync(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	commitID := "foobar"
+	noti := notifier.NewMock()
+	noti.Statuses[commitID+"/sync"] = &notifier.Status{State: notifier.EventStateFailed}
+	events := make(chan notifier.Event, 1)
+
+	r := NewReconciler(logr.TestLogger{T: t}, noti, newClient(commitID), events, 1)
+	err := r.Reconcile(context.TODO())
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(events).ShouldNot(gomega.Receive())
+	g.Expect(noti.Events).ShouldNot(gomega.Receive())
+}
+
+func TestReconcileUnsynced(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	commitID := "foobar"
+	noti := notifier.NewMock()
+	client := newClient(commitID)
+	client.Unsynced = []string{"barfoo"}
+	events := make(chan notifier.Event, 1)
+
+	r := NewReconciler(logr.TestLogger{T: t}, noti, client, events, 1)
+	err := r.Reconcile(context.TODO())
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(events).ShouldNot(gomega.Receive())
+	g.Expect(noti.Events).ShouldNot(gomega.Receive())
+}
+
+func TestReconcileHeadAhead(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	// The git provider has a new head that Flux has not fetched and synced yet
+	noti := notifier.NewMock()
+	noti.Revisions = map[string]string{"master": "foobar"}
+	client := newClient("master")
+	client.UnsyncedRefs = map[string][]string{"foobar": {"foobar"}}
+	events := make(chan notifier.Event, 1)
+
+	r := NewReconciler(logr.TestLogger{T: t}, noti, client, events, 1)
+	err := r.Reconcile(context.TODO())
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(events).ShouldNot(gomega.Receive())
+	g.Expect(noti.Events).ShouldNot(gomega.Receive())
+}
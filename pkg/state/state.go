@@ -0,0 +1,188 @@
+package state
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xenitab/flux-status/pkg/notifier"
+)
+
+// Status is the latest known event of a specific type for a commit.
+type Status struct {
+	State     notifier.EventState      `json:"state"`
+	Message   string                   `json:"message"`
+	Errors    []notifier.ResourceError `json:"errors,omitempty"`
+	Pending   []string                 `json:"pending,omitempty"`
+	UpdatedAt time.Time                `json:"updatedAt"`
+}
+
+// Commit is the latest known state of a commit.
+type Commit struct {
+	CommitID  string                        `json:"commitId"`
+	Statuses  map[notifier.EventType]Status `json:"statuses"`
+	UpdatedAt time.Time                     `json:"updatedAt"`
+}
+
+// Instance is the latest known state of the flux-status instance.
+type Instance struct {
+	Instance string  `json:"instance"`
+	Current  *Commit `json:"current"`
+}
+
+// State keeps the latest known state of the most recent commits in memory.
+type State struct {
+	instance string
+	limit    int
+
+	mu      sync.RWMutex
+	commits map[string]*Commit
+	order   []string
+	current string
+}
+
+// NewState creates and returns a State instance keeping at most limit commits.
+func NewState(inst string, limit int) *State {
+	return &State{
+		instance: inst,
+		limit:    limit,
+		commits:  map[string]*Commit{},
+		order:    []string{},
+	}
+}
+
+// Record updates the state of the commit with the event. The head commit of the
+// latest sync event is considered to be the current commit.
+func (s *State) Record(e notifier.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	commit, ok := s.commits[e.CommitID]
+	if !ok {
+		commit = &Commit{
+			CommitID: e.CommitID,
+			Statuses: map[notifier.EventType]Status{},
+		}
+		s.commits[e.CommitID] = commit
+		s.order = append(s.order, e.CommitID)
+		s.evict()
+	}
+
+	pending := []string{}
+	for _, id := range e.Pending {
+		pending = append(pending, id.String())
+	}
+	commit.Statuses[e.Type] = Status{
+		State:     e.State,
+		Message:   e.Message,
+		Errors:    e.Errors,
+		Pending:   pending,
+		UpdatedAt: now,
+	}
+	commit.UpdatedAt = now
+
+	if e.Type == notifier.EventTypeSync && e.IncludedIn == "" {
+		s.current = e.CommitID
+	}
+}
+
+// Commit returns a copy of the commit matching the commit id or a unique prefix of it.
+func (s *State) Commit(commitID string) (*Commit, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if commit, ok := s.commits[commitID]; ok {
+		return commit.copy(), true
+	}
+
+	var match *Commit
+	for id, commit := range s.commits {
+		if !strings.HasPrefix(id, commitID) {
+			continue
+		}
+		if match != nil {
+			return nil, false
+		}
+		match = commit
+	}
+	if match == nil {
+		return nil, false
+	}
+
+	return match.copy(), true
+}
+
+// Commits returns a copy of all commits, starting with the most recently added.
+func (s *State) Commits() []*Commit {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	commits := []*Commit{}
+	for i := len(s.order) - 1; i >= 0; i-- {
+		commits = append(commits, s.commits[s.order[i]].copy())
+	}
+
+	return commits
+}
+
+// Instance returns the state of the instance and its current commit.
+func (s *State) Instance() Instance {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	instance := Instance{
+		Instance: s.instance,
+	}
+	if commit, ok := s.commits[s.current]; ok {
+		instance.Current = commit.copy()
+	}
+
+	return instance
+}
+
+// evict removes the oldest commits until the limit is met, it expects the lock to be held.
+func (s *State) evict() {
+	for s.limit > 0 && len(s.order) > s.limit {
+		delete(s.commits, s.order[0])
+		s.order = s.order[1:]
+	}
+}
+
+func (c *Commit) copy() *Commit {
+	statuses := map[notifier.EventType]Status{}
+	for k, v := range c.Statuses {
+		statuses[k] = v
+	}
+
+	return &Commit{
+		CommitID:  c.CommitID,
+		Statuses:  statuses,
+		UpdatedAt: c.UpdatedAt,
+	}
+}
+
+// Recorder wraps a Notifier and records every event successfully sent in the State.
+type Recorder struct {
+	notifier.Notifier
+	State *State
+}
+
+// NewRecorder creates and returns a Recorder instance.
+func NewRecorder(n notifier.Notifier, s *State) *Recorder {
+	return &Recorder{
+		Notifier: n,
+		State:    s,
+	}
+}
+
+// Send sends the event through the wrapped Notifier and records it if it was sent.
+func (r Recorder) Send(ctx context.Context, e notifier.Event) error {
+	if err := r.Notifier.Send(ctx, e); err != nil {
+		return err
+	}
+
+	r.State.Record(e)
+	return nil
+}
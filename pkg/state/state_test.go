@@ -0,0 +1,107 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/fluxcd/flux/pkg/resource"
+	"github.com/onsi/gomega"
+
+	"github.com/xenitab/flux-status/pkg/notifier"
+)
+
+func TestRecordCurrent(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	s := NewState("dev", 10)
+
+	s.Record(notifier.Event{Type: notifier.EventTypeSync, CommitID: "foo", State: notifier.EventStateSucceeded})
+	s.Record(notifier.Event{Type: notifier.EventTypeSync, CommitID: "bar", State: notifier.EventStateSucceeded})
+	s.Record(notifier.Event{
+		Type:     notifier.EventTypeWorkload,
+		CommitID: "bar",
+		State:    notifier.EventStateFailed,
+		Pending:  []resource.ID{resource.MustParseID("namespace:deployment/app")},
+	})
+
+	instance := s.Instance()
+	g.Expect(instance.Instance).Should(gomega.Equal("dev"))
+	g.Expect(instance.Current.CommitID).Should(gomega.Equal("bar"))
+	g.Expect(instance.Current.Statuses).Should(gomega.HaveLen(2))
+	g.Expect(instance.Current.Statuses[notifier.EventTypeWorkload].State).Should(gomega.Equal(notifier.EventStateFailed))
+	g.Expect(instance.Current.Statuses[notifier.EventTypeWorkload].Pending).Should(gomega.ConsistOf("namespace:deployment/app"))
+}
+
+func TestRecordIncluded(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	s := NewState("dev", 10)
+
+	// Commits included in a sync are sent after the head commit
+	s.Record(notifier.Event{Type: notifier.EventTypeSync, CommitID: "bar", State: notifier.EventStateSucceeded})
+	s.Record(notifier.Event{Type: notifier.EventTypeSync, CommitID: "foo", State: notifier.EventStateSucceeded, IncludedIn: "bar"})
+
+	g.Expect(s.Instance().Current.CommitID).Should(gomega.Equal("bar"))
+	_, ok := s.Commit("foo")
+	g.Expect(ok).Should(gomega.BeTrue())
+}
+
+func TestCommitPrefix(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	s := NewState("dev", 10)
+	s.Record(notifier.Event{Type: notifier.EventTypeSync, CommitID: "abc123"})
+	s.Record(notifier.Event{Type: notifier.EventTypeSync, CommitID: "abd123"})
+
+	commit, ok := s.Commit("abc")
+	g.Expect(ok).Should(gomega.BeTrue())
+	g.Expect(commit.CommitID).Should(gomega.Equal("abc123"))
+
+	_, ok = s.Commit("ab")
+	g.Expect(ok).Should(gomega.BeFalse())
+	_, ok = s.Commit("foo")
+	g.Expect(ok).Should(gomega.BeFalse())
+}
+
+func TestEvict(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	s := NewState("dev", 2)
+	for _, id := range []string{"foo", "bar", "baz"} {
+		s.Record(notifier.Event{Type: notifier.EventTypeSync, CommitID: id})
+	}
+
+	_, ok := s.Commit("foo")
+	g.Expect(ok).Should(gomega.BeFalse())
+	_, ok = s.Commit("baz")
+	g.Expect(ok).Should(gomega.BeTrue())
+
+	commits := s.Commits()
+	g.Expect(commits).Should(gomega.HaveLen(2))
+	g.Expect(commits[0].CommitID).Should(gomega.Equal("baz"))
+	g.Expect(commits[1].CommitID).Should(gomega.Equal("bar"))
+}
+
+func TestRecorder(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	s := NewState("dev", 10)
+	mock := notifier.NewMock()
+	r := NewRecorder(mock, s)
+
+	e := notifier.Event{Type: notifier.EventTypeSync, CommitID: "foo", State: notifier.EventStateSucceeded}
+	err := r.Send(context.TODO(), e)
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(mock.Events).Should(gomega.Receive(gomega.Equal(e)))
+	_, ok := s.Commit("foo")
+	g.Expect(ok).Should(gomega.BeTrue())
+}
+
+func TestRecorderSendError(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	s := NewState("dev", 10)
+	mock := notifier.NewMock()
+	mock.SendErr = errors.New("provider unavailable")
+	r := NewRecorder(mock, s)
+
+	err := r.Send(context.TODO(), notifier.Event{Type: notifier.EventTypeSync, CommitID: "foo", State: notifier.EventStateSucceeded})
+	g.Expect(err).Should(gomega.HaveOccurred())
+	_, ok := s.Commit("foo")
+	g.Expect(ok).Should(gomega.BeFalse())
+}
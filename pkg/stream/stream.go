@@ -0,0 +1,179 @@
+package stream
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/xenitab/flux-status/pkg/notifier"
+)
+
+// TypePoll is the message type for workload poll progress.
+const TypePoll = "poll"
+
+// subscriberBuffer is the amount of messages a subscriber can lag behind before it is dropped.
+const subscriberBuffer = 100
+
+// Message is a single status transition published to subscribers.
+type Message struct {
+	ID       uint64                   `json:"id"`
+	Type     string                   `json:"type"`
+	CommitID string                   `json:"commitId"`
+	State    notifier.EventState      `json:"state"`
+	Message  string                   `json:"message"`
+	Errors   []notifier.ResourceError `json:"errors,omitempty"`
+	Pending  []string                 `json:"pending,omitempty"`
+	Time     time.Time                `json:"time"`
+}
+
+// Broker publishes messages to subscribers and keeps a bounded buffer of the
+// most recent messages so that subscribers can replay missed messages. Message
+// ids restart after a restart, so they are only unique together with the epoch.
+type Broker struct {
+	size  int
+	epoch int64
+
+	mu          sync.Mutex
+	buffer      []Message
+	nextID      uint64
+	subscribers map[chan Message]struct{}
+	closed      bool
+}
+
+// NewBroker creates and returns a Broker instance buffering size messages.
+func NewBroker(size int) *Broker {
+	return &Broker{
+		size:        size,
+		epoch:       time.Now().UnixNano(),
+		buffer:      []Message{},
+		nextID:      1,
+		subscribers: map[chan Message]struct{}{},
+	}
+}
+
+// Epoch returns the time the broker was created, which identifies the sequence of message ids.
+func (b *Broker) Epoch() int64 {
+	return b.epoch
+}
+
+// Publish assigns an id to the message and sends it to all subscribers.
+// Subscribers that are not keeping up are dropped, they are expected to
+// reconnect and replay the missed messages.
+func (b *Broker) Publish(m Message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+
+	m.ID = b.nextID
+	b.nextID++
+	if m.Time.IsZero() {
+		m.Time = time.Now()
+	}
+
+	b.buffer = append(b.buffer, m)
+	if len(b.buffer) > b.size {
+		b.buffer = b.buffer[len(b.buffer)-b.size:]
+	}
+
+	for sub := range b.subscribers {
+		select {
+		case sub <- m:
+		default:
+			delete(b.subscribers, sub)
+			close(sub)
+		}
+	}
+}
+
+// PublishEvent publishes the notifier event.
+func (b *Broker) PublishEvent(e notifier.Event) {
+	pending := []string{}
+	for _, id := range e.Pending {
+		pending = append(pending, id.String())
+	}
+
+	b.Publish(Message{
+		Type:     string(e.Type),
+		CommitID: e.CommitID,
+		State:    e.State,
+		Message:  e.Message,
+		Errors:   e.Errors,
+		Pending:  pending,
+	})
+}
+
+// Subscribe returns the buffered messages published after lastID and a channel
+// receiving all following messages. The channel is closed when the subscriber
+// is dropped or the broker is closed.
+func (b *Broker) Subscribe(lastID uint64) ([]Message, <-chan Message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	replay := []Message{}
+	for _, m := range b.buffer {
+		if m.ID > lastID {
+			replay = append(replay, m)
+		}
+	}
+
+	sub := make(chan Message, subscriberBuffer)
+	if b.closed {
+		close(sub)
+		return replay, sub
+	}
+	b.subscribers[sub] = struct{}{}
+
+	return replay, sub
+}
+
+// Unsubscribe stops sending messages to the channel.
+func (b *Broker) Unsubscribe(sub <-chan Message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for s := range b.subscribers {
+		if s == sub {
+			delete(b.subscribers, s)
+			close(s)
+			return
+		}
+	}
+}
+
+// Close closes all subscriber channels and stops publishing messages.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for sub := range b.subscribers {
+		delete(b.subscribers, sub)
+		close(sub)
+	}
+}
+
+// Publisher wraps a Notifier and publishes every event sent to the Broker.
+type Publisher struct {
+	notifier.Notifier
+	Broker *Broker
+}
+
+// NewPublisher creates and returns a Publisher instance.
+func NewPublisher(n notifier.Notifier, b *Broker) *Publisher {
+	return &Publisher{
+		Notifier: n,
+		Broker:   b,
+	}
+}
+
+// Send sends the event through the wrapped Notifier and publishes it if it was sent.
+func (p Publisher) Send(ctx context.Context, e notifier.Event) error {
+	if err := p.Notifier.Send(ctx, e); err != nil {
+		return err
+	}
+
+	p.Broker.PublishEvent(e)
+	return nil
+}
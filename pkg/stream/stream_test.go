@@ -0,0 +1,92 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/onsi/gomega"
+
+	"github.com/xenitab/flux-status/pkg/notifier"
+)
+
+func TestReplay(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	b := NewBroker(2)
+	b.Publish(Message{CommitID: "foo"})
+	b.Publish(Message{CommitID: "bar"})
+	b.Publish(Message{CommitID: "baz"})
+
+	replay, _ := b.Subscribe(0)
+	g.Expect(replay).Should(gomega.HaveLen(2))
+	g.Expect(replay[0].ID).Should(gomega.Equal(uint64(2)))
+	g.Expect(replay[1].CommitID).Should(gomega.Equal("baz"))
+
+	replay, _ = b.Subscribe(2)
+	g.Expect(replay).Should(gomega.HaveLen(1))
+	g.Expect(replay[0].ID).Should(gomega.Equal(uint64(3)))
+}
+
+func TestSubscribe(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	b := NewBroker(10)
+	_, sub := b.Subscribe(0)
+
+	b.PublishEvent(notifier.Event{Type: notifier.EventTypeSync, CommitID: "foo", State: notifier.EventStateSucceeded})
+	m := <-sub
+	g.Expect(m.Type).Should(gomega.Equal("sync"))
+	g.Expect(m.CommitID).Should(gomega.Equal("foo"))
+	g.Expect(m.Time.IsZero()).Should(gomega.BeFalse())
+
+	b.Unsubscribe(sub)
+	_, ok := <-sub
+	g.Expect(ok).Should(gomega.BeFalse())
+}
+
+func TestDropSlowSubscriber(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	b := NewBroker(10)
+	_, sub := b.Subscribe(0)
+
+	for i := 0; i <= subscriberBuffer; i++ {
+		b.Publish(Message{})
+	}
+
+	for range sub {
+	}
+	b.Unsubscribe(sub)
+	g.Expect(b.subscribers).Should(gomega.BeEmpty())
+}
+
+func TestClose(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	b := NewBroker(10)
+	_, sub := b.Subscribe(0)
+
+	b.Close()
+	_, ok := <-sub
+	g.Expect(ok).Should(gomega.BeFalse())
+	b.Publish(Message{})
+	replay, _ := b.Subscribe(0)
+	g.Expect(replay).Should(gomega.BeEmpty())
+}
+
+func TestPublisher(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	b := NewBroker(10)
+	noti := notifier.NewMock()
+	p := NewPublisher(noti, b)
+
+	noti.SendErr = errors.New("provider unavailable")
+	err := p.Send(context.TODO(), notifier.Event{Type: notifier.EventTypeSync, CommitID: "foo"})
+	g.Expect(err).Should(gomega.HaveOccurred())
+	replay, _ := b.Subscribe(0)
+	g.Expect(replay).Should(gomega.BeEmpty())
+
+	noti.SendErr = nil
+	err = p.Send(context.TODO(), notifier.Event{Type: notifier.EventTypeSync, CommitID: "bar"})
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	replay, _ = b.Subscribe(0)
+	g.Expect(replay).Should(gomega.HaveLen(1))
+	g.Expect(replay[0].CommitID).Should(gomega.Equal("bar"))
+}
@@ -0,0 +1,128 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/api/global"
+	"go.opentelemetry.io/otel/api/trace"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp"
+	"go.opentelemetry.io/otel/exporters/stdout"
+	"go.opentelemetry.io/otel/label"
+	export "go.opentelemetry.io/otel/sdk/export/trace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/semconv"
+
+	"github.com/xenitab/flux-status/pkg/notifier"
+)
+
+// tracerName is the name of the tracer used for all spans.
+const tracerName = "github.com/xenitab/flux-status"
+
+// These constants represents all valid exporters.
+const (
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+// CommitIDKey is the span attribute key of the commit id.
+const CommitIDKey = label.Key("commit.id")
+
+// Setup registers a global tracer provider exporting spans with the exporter and
+// sampling the given ratio of traces. The returned function flushes and stops the
+// exporter, it should be called before exiting.
+func Setup(exporter string, endpoint string, ratio float64) (func(context.Context) error, error) {
+	var spanExporter export.SpanExporter
+	switch exporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		opts := []otlp.ExporterOption{otlp.WithInsecure()}
+		if endpoint != "" {
+			opts = append(opts, otlp.WithAddress(endpoint))
+		}
+		exp, err := otlp.NewExporter(opts...)
+		if err != nil {
+			return nil, err
+		}
+		spanExporter = exp
+	case ExporterStdout:
+		exp, err := stdout.NewExporter(stdout.WithPrettyPrint(), stdout.WithoutMetricExport())
+		if err != nil {
+			return nil, err
+		}
+		spanExporter = exp
+	default:
+		return nil, fmt.Errorf("Unknown tracing exporter %q", exporter)
+	}
+
+	bsp := sdktrace.NewBatchSpanProcessor(spanExporter)
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithConfig(sdktrace.Config{DefaultSampler: sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))}),
+		sdktrace.WithResource(resource.New(semconv.ServiceNameKey.String("flux-status"))),
+		sdktrace.WithSpanProcessor(bsp),
+	)
+	global.SetTracerProvider(tp)
+
+	return func(ctx context.Context) error {
+		bsp.Shutdown()
+		return spanExporter.Shutdown(ctx)
+	}, nil
+}
+
+// Start creates a span with the commit id as an attribute, the commit id is omitted if empty.
+func Start(ctx context.Context, name string, commitID string, attrs ...label.KeyValue) (context.Context, trace.Span) {
+	if commitID != "" {
+		attrs = append(attrs, CommitIDKey.String(commitID))
+	}
+
+	return global.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records the error if it is not nil and ends the span.
+func End(ctx context.Context, span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(ctx, err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Notifier wraps a Notifier and creates a span for every request to the git provider.
+type Notifier struct {
+	notifier.Notifier
+}
+
+// NewNotifier creates and returns a Notifier instance.
+func NewNotifier(n notifier.Notifier) *Notifier {
+	return &Notifier{
+		Notifier: n,
+	}
+}
+
+// Send sends the event through the wrapped Notifier within a span.
+func (n Notifier) Send(ctx context.Context, e notifier.Event) error {
+	ctx, span := Start(ctx, "notifier.send", e.CommitID, n.attributes(e)...)
+	err := n.Notifier.Send(ctx, e)
+	End(ctx, span, err)
+	return err
+}
+
+// Comment comments the event through the wrapped Notifier within a span.
+func (n Notifier) Comment(ctx context.Context, e notifier.Event) error {
+	ctx, span := Start(ctx, "notifier.comment", e.CommitID, n.attributes(e)...)
+	err := n.Notifier.Comment(ctx, e)
+	End(ctx, span, err)
+	return err
+}
+
+func (n Notifier) attributes(e notifier.Event) []label.KeyValue {
+	return []label.KeyValue{
+		label.String("notifier.provider", n.Notifier.String()),
+		label.String("event.type", string(e.Type)),
+		label.String("event.state", string(e.State)),
+	}
+}
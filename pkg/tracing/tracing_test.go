@@ -0,0 +1,40 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/onsi/gomega"
+
+	"github.com/xenitab/flux-status/pkg/notifier"
+)
+
+func TestSetup(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	_, err := Setup("foo", "", 1)
+	g.Expect(err).Should(gomega.HaveOccurred())
+
+	shutdown, err := Setup(ExporterNone, "", 1)
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(shutdown(context.TODO())).Should(gomega.Succeed())
+
+	shutdown, err = Setup(ExporterStdout, "", 1)
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	_, span := Start(context.TODO(), "test", "foo")
+	g.Expect(span.SpanContext().HasTraceID()).Should(gomega.BeTrue())
+	span.End()
+	g.Expect(shutdown(context.TODO())).Should(gomega.Succeed())
+}
+
+func TestNotifier(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	mock := notifier.NewMock()
+	n := NewNotifier(mock)
+
+	e := notifier.Event{Type: notifier.EventTypeSync, CommitID: "foo", State: notifier.EventStateFailed}
+	g.Expect(n.Send(context.TODO(), e)).Should(gomega.Succeed())
+	g.Expect(mock.Events).Should(gomega.Receive(gomega.Equal(e)))
+	g.Expect(n.Comment(context.TODO(), e)).Should(gomega.Succeed())
+	g.Expect(mock.Comments).Should(gomega.Receive(gomega.Equal(e)))
+}